@@ -0,0 +1,280 @@
+// Package cr2 reads Canon CR2 RAW files: their Exif and maker note
+// directories, the embedded JPEG previews and the size of the sensor data.
+//
+// A CR2 file is a TIFF file with four IFDs: the first describes the
+// full-size JPEG preview and points to the Exif IFD, the second holds a
+// small JPEG thumbnail, the third an uncompressed RGB image and the fourth
+// the lossless JPEG compressed sensor data.
+package cr2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// Returned when data is not a CR2 file
+var ErrFormat = errors.New("cr2: not a CR2 file")
+
+// Size of the lossless JPEG header read to find the sensor dimensions
+const rawHeaderSize = 4096
+
+// A decoded CR2 file
+type File struct {
+	r *tiff.Reader
+
+	// Version of the CR2 format, normally 2.0
+	MajorVersion int
+	MinorVersion int
+
+	// Directories of the file; Exif, GPS and MakerNote are nil if missing.
+	// The maker note is a Canon IFD whose offsets, like the others, are
+	// relative to the start of the file.
+	IFDs      []*tiff.IFD
+	Exif      *tiff.IFD
+	GPS       *tiff.IFD
+	MakerNote *tiff.IFD
+
+	Make  string
+	Model string
+
+	// Capture time, in tiff.CameraLocation unless the file records its
+	// offset from UTC
+	Time time.Time
+
+	// Exif orientation, 1 when the picture is upright
+	Orientation int
+
+	// Exposure time in seconds, f-number, ISO speed and focal length in mm
+	ExposureTime float64
+	FNumber      float64
+	ISO          int
+	FocalLength  float64
+
+	// Width and height in pixels of the sensor data, including the masked
+	// borders
+	SensorWidth  int
+	SensorHeight int
+}
+
+// Decode the directories of a CR2 file.  The images themselves aren't read
+// until asked for.
+func Decode(r io.ReaderAt) (*File, error) {
+	reader, header, err := newReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{r: reader, MajorVersion: int(header[2]), MinorVersion: int(header[3]), Orientation: 1}
+	if f.IFDs, err = reader.ReadIFDs(); err != nil {
+		return nil, err
+	}
+	if len(f.IFDs) < 4 {
+		return nil, errors.New(fmt.Sprintf("cr2: expected 4 IFDs, found %d", len(f.IFDs)))
+	}
+	if f.Exif, err = reader.ReadSubIFD(f.IFDs[0], tiff.ExifIFDPointer); err != nil {
+		return nil, err
+	}
+	if f.GPS, err = reader.ReadSubIFD(f.IFDs[0], tiff.GPSIFDPointer); err != nil {
+		return nil, err
+	}
+	if entry := f.Exif.Find(tiff.MakerNote); entry != nil {
+		if f.MakerNote, err = reader.ReadIFD(entry.Offset); err != nil {
+			return nil, err
+		}
+	}
+
+	f.readFields()
+	if err := f.readSensorSize(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Offset and length of the full-size preview, found from the first IFD
+// alone, so r need only hold the start of the file, such as while it is
+// still being downloaded
+func PreviewRange(r io.ReaderAt) (int64, int64, error) {
+	reader, _, err := newReader(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	ifd, err := reader.ReadIFD(reader.First)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, length, err := strip(ifd)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(offset), int64(length), nil
+}
+
+// Read the TIFF header and the CR2 header following it, which holds the
+// version
+func newReader(r io.ReaderAt) (*tiff.Reader, []byte, error) {
+	reader, err := tiff.NewReader(r)
+	if err != nil {
+		return nil, nil, ErrFormat
+	}
+	header, err := reader.Bytes(8, 4)
+	if err != nil || header[0] != 'C' || header[1] != 'R' {
+		return nil, nil, ErrFormat
+	}
+	return reader, header, nil
+}
+
+// The reader of the file's TIFF structure, for following other tags
+func (f *File) Reader() *tiff.Reader {
+	return f.r
+}
+
+// Fill in the common fields, leaving those the file doesn't have at their
+// zero values
+func (f *File) readFields() {
+	if entry := f.IFDs[0].Find(tiff.Make); entry != nil {
+		f.Make = entry.String()
+	}
+	if entry := f.IFDs[0].Find(tiff.Model); entry != nil {
+		f.Model = entry.String()
+	}
+	if entry := f.IFDs[0].Find(tiff.Orientation); entry != nil {
+		if value, err := entry.Uint(0); err == nil {
+			f.Orientation = int(value)
+		}
+	}
+
+	taken := f.Exif.Find(tiff.DateTimeOriginal)
+	if taken == nil {
+		taken = f.IFDs[0].Find(tiff.DateTime)
+	}
+	if taken != nil {
+		var subSeconds, offset string
+		if entry := f.Exif.Find(tiff.SubSecTimeOriginal); entry != nil {
+			subSeconds = entry.String()
+		}
+		if entry := f.Exif.Find(tiff.OffsetTimeOriginal); entry != nil {
+			offset = entry.String()
+		}
+		f.Time = tiff.ParseTime(taken.String(), subSeconds, offset)
+	}
+
+	if entry := f.Exif.Find(tiff.ExposureTime); entry != nil {
+		f.ExposureTime, _ = entry.Float(0)
+	}
+	if entry := f.Exif.Find(tiff.FNumber); entry != nil {
+		f.FNumber, _ = entry.Float(0)
+	}
+	if entry := f.Exif.Find(tiff.ISOSpeedRatings); entry != nil {
+		if value, err := entry.Uint(0); err == nil {
+			f.ISO = int(value)
+		}
+	}
+	if entry := f.Exif.Find(tiff.FocalLength); entry != nil {
+		f.FocalLength, _ = entry.Float(0)
+	}
+}
+
+// Read the sensor size from the start of frame header of the lossless JPEG
+// holding the sensor data.  Each line of the JPEG holds several components,
+// so the sensor is that many times wider than the JPEG says.
+func (f *File) readSensorSize() error {
+	offset, _, err := strip(f.IFDs[3])
+	if err != nil {
+		return err
+	}
+	// a small file may end within the header size
+	data := make([]byte, rawHeaderSize)
+	n, err := f.r.ReadAt(data, int64(offset))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	data = data[:n]
+
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return errors.New("cr2: sensor data is not a lossless JPEG")
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			break
+		}
+		marker := data[i+1]
+		length := int(data[i+2])<<8 | int(data[i+3])
+		if marker == 0xC3 && i+10 <= len(data) {
+			height := int(data[i+5])<<8 | int(data[i+6])
+			width := int(data[i+7])<<8 | int(data[i+8])
+			components := int(data[i+9])
+			f.SensorWidth, f.SensorHeight = width*components, height
+			return nil
+		}
+		if marker == 0xDA {
+			break
+		}
+		i += 2 + length
+	}
+	return errors.New("cr2: sensor data has no lossless JPEG frame header")
+}
+
+// The full-size JPEG preview
+func (f *File) Preview() ([]byte, error) {
+	offset, length, err := strip(f.IFDs[0])
+	if err != nil {
+		return nil, err
+	}
+	return f.r.Bytes(offset, length)
+}
+
+// Width and height of the full-size preview
+func (f *File) PreviewSize() (int, int) {
+	width, height := 0, 0
+	if entry := f.IFDs[0].Find(tiff.ImageWidth); entry != nil {
+		if value, err := entry.Uint(0); err == nil {
+			width = int(value)
+		}
+	}
+	if entry := f.IFDs[0].Find(tiff.ImageLength); entry != nil {
+		if value, err := entry.Uint(0); err == nil {
+			height = int(value)
+		}
+	}
+	return width, height
+}
+
+// The small JPEG thumbnail, normally 160x120
+func (f *File) Thumbnail() ([]byte, error) {
+	offset := f.IFDs[1].Find(tiff.JPEGInterchangeFormat)
+	length := f.IFDs[1].Find(tiff.JPEGInterchangeFormatLength)
+	if offset == nil || length == nil {
+		return nil, errors.New("cr2: file has no thumbnail")
+	}
+	start, err := offset.Uint(0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := length.Uint(0)
+	if err != nil {
+		return nil, err
+	}
+	return f.r.Bytes(start, size)
+}
+
+// Offset and length of the single strip of an image
+func strip(ifd *tiff.IFD) (uint32, uint32, error) {
+	offsets := ifd.Find(tiff.StripOffsets)
+	counts := ifd.Find(tiff.StripByteCounts)
+	if offsets == nil || counts == nil {
+		return 0, 0, errors.New(fmt.Sprintf("cr2: IFD at offset %d has no image strip", ifd.Offset))
+	}
+	offset, err := offsets.Uint(0)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := counts.Uint(0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return offset, length, nil
+}
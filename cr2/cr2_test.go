@@ -0,0 +1,151 @@
+package cr2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+var (
+	testPreview   = []byte{0xFF, 0xD8, 0xFF, 0xD9, 'p', 'r', 'e', 'v', 'i', 'e', 'w'}
+	testThumbnail = []byte{0xFF, 0xD8, 0xFF, 0xD9, 't', 'h', 'u', 'm', 'b'}
+
+	// start of a lossless JPEG with a DHT segment ahead of the frame header:
+	// 2 components of 2784 samples on 3950 lines
+	testSensorData = []byte{
+		0xFF, 0xD8,
+		0xFF, 0xC4, 0x00, 0x04, 0x00, 0x00,
+		0xFF, 0xC3, 0x00, 0x0E, 0x0E, 0x0F, 0x6E, 0x0A, 0xE0, 0x02, 0x01, 0x11, 0x00, 0x02, 0x11, 0x00,
+		0xFF, 0xDA, 0x00, 0x02,
+	}
+)
+
+func buildTestCR2() []byte {
+	b := tifftest.New(binary.LittleEndian)
+	// rest of the CR2 header: version 2.0 and the offset of the RAW IFD
+	b.Append([]byte{'C', 'R', 2, 0, 0, 0, 0, 0})
+
+	preview := b.Append(testPreview)
+	thumbnail := b.Append(testThumbnail)
+	sensor := b.Append(testSensorData)
+
+	// the maker note is a copy of this IFD, its value offsets still
+	// pointing at the model name written with the original
+	canon := b.IFD([]tifftest.Entry{b.ASCII(0x0006, "Canon EOS 5D Mark III")}, 0)
+	makerNote := append([]byte(nil), b.Bytes()[canon:]...)
+	exif := b.IFD([]tifftest.Entry{
+		b.Rational(tiff.ExposureTime, 1, 200),
+		b.Rational(tiff.FNumber, 56, 10),
+		b.Short(tiff.ISOSpeedRatings, 800),
+		b.ASCII(tiff.DateTimeOriginal, "2015:06:01 12:30:45"),
+		b.Rational(tiff.FocalLength, 50, 1),
+		b.Undefined(tiff.MakerNote, makerNote),
+	}, 0)
+
+	raw := b.IFD([]tifftest.Entry{
+		b.Short(tiff.Compression, 6),
+		b.Long(tiff.StripOffsets, sensor),
+		b.Long(tiff.StripByteCounts, uint32(len(testSensorData))),
+	}, 0)
+	rgb := b.IFD([]tifftest.Entry{b.Short(tiff.ImageWidth, 592)}, raw)
+	small := b.IFD([]tifftest.Entry{
+		b.Long(tiff.JPEGInterchangeFormat, thumbnail),
+		b.Long(tiff.JPEGInterchangeFormatLength, uint32(len(testThumbnail))),
+	}, rgb)
+	first := b.IFD([]tifftest.Entry{
+		b.Short(tiff.ImageWidth, 5760),
+		b.Short(tiff.ImageLength, 3840),
+		b.ASCII(tiff.Make, "Canon"),
+		b.ASCII(tiff.Model, "Canon EOS 5D Mark III"),
+		b.Long(tiff.StripOffsets, preview),
+		b.Short(tiff.Orientation, 6),
+		b.Long(tiff.StripByteCounts, uint32(len(testPreview))),
+		b.Long(tiff.ExifIFDPointer, exif),
+	}, small)
+
+	b.SetFirst(first)
+	b.PutUint32(12, raw)
+	return b.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	f, err := Decode(bytes.NewReader(buildTestCR2()))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, f.MajorVersion)
+	assert.Equal(t, 0, f.MinorVersion)
+	assert.Equal(t, 4, len(f.IFDs))
+	assert.Equal(t, "Canon", f.Make)
+	assert.Equal(t, "Canon EOS 5D Mark III", f.Model)
+	assert.Equal(t, 6, f.Orientation)
+	assert.Equal(t, time.Date(2015, 6, 1, 12, 30, 45, 0, time.Local), f.Time)
+	assert.Equal(t, 0.005, f.ExposureTime)
+	assert.Equal(t, 5.6, f.FNumber)
+	assert.Equal(t, 800, f.ISO)
+	assert.Equal(t, 50.0, f.FocalLength)
+	assert.Nil(t, f.GPS)
+
+	assert.NotNil(t, f.MakerNote)
+	assert.Equal(t, "Canon EOS 5D Mark III", f.MakerNote.Find(0x0006).String())
+}
+
+func TestDecodeCameraLocation(t *testing.T) {
+	defer func(location *time.Location) { tiff.CameraLocation = location }(tiff.CameraLocation)
+	tiff.CameraLocation = time.FixedZone("+10:00", 10*3600)
+
+	f, err := Decode(bytes.NewReader(buildTestCR2()))
+	assert.Nil(t, err)
+	assert.True(t, f.Time.Equal(time.Date(2015, 6, 1, 2, 30, 45, 0, time.UTC)))
+}
+
+func TestSensorSize(t *testing.T) {
+	f, err := Decode(bytes.NewReader(buildTestCR2()))
+	assert.Nil(t, err)
+	assert.Equal(t, 5568, f.SensorWidth)
+	assert.Equal(t, 3950, f.SensorHeight)
+}
+
+func TestPreviewAndThumbnail(t *testing.T) {
+	f, err := Decode(bytes.NewReader(buildTestCR2()))
+	assert.Nil(t, err)
+
+	preview, err := f.Preview()
+	assert.Nil(t, err)
+	assert.Equal(t, testPreview, preview)
+	width, height := f.PreviewSize()
+	assert.Equal(t, 5760, width)
+	assert.Equal(t, 3840, height)
+
+	thumbnail, err := f.Thumbnail()
+	assert.Nil(t, err)
+	assert.Equal(t, testThumbnail, thumbnail)
+}
+
+func TestPreviewRange(t *testing.T) {
+	data := buildTestCR2()
+	first := binary.LittleEndian.Uint32(data[4:])
+
+	// the first IFD and the values it points to are enough
+	offset, length, err := PreviewRange(bytes.NewReader(data[:first+2+8*12+4]))
+	assert.Nil(t, err)
+	assert.Equal(t, testPreview, data[offset:offset+length])
+
+	_, _, err = PreviewRange(bytes.NewReader(data[:first]))
+	assert.NotNil(t, err)
+	_, _, err = PreviewRange(bytes.NewReader([]byte("II*\x00\x08\x00\x00\x00")))
+	assert.Equal(t, ErrFormat, err)
+}
+
+func TestNotCR2(t *testing.T) {
+	b := tifftest.New(binary.LittleEndian)
+	b.SetFirst(b.IFD([]tifftest.Entry{b.Short(tiff.ImageWidth, 1)}, 0))
+	_, err := Decode(bytes.NewReader(b.Bytes()))
+	assert.Equal(t, ErrFormat, err)
+
+	_, err = Decode(bytes.NewReader([]byte("\xFF\xD8\xFF\xE1")))
+	assert.Equal(t, ErrFormat, err)
+}
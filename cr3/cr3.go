@@ -0,0 +1,342 @@
+// Package cr3 reads Canon CR3 RAW files: their Exif and maker note
+// directories and the embedded JPEG preview and thumbnail.
+//
+// A CR3 file is an ISO base media file.  Its moov box holds a Canon uuid box
+// whose CMT1 to CMT4 boxes are complete TIFF structures for IFD0, the Exif
+// IFD, the maker note and the GPS IFD, next to a THMB box with a 160x120
+// thumbnail.  The full-size preview sits in a PRVW box in a second uuid box
+// at the top level.
+package cr3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// Returned when data is not a CR3 file
+var ErrFormat = errors.New("cr3: not a CR3 file")
+
+var (
+	// uuid of the box in moov holding the metadata and thumbnail
+	canonUUID = []byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+	// uuid of the top level box holding the preview
+	previewUUID = []byte{0xea, 0xf4, 0x2b, 0x5e, 0x1c, 0x98, 0x4b, 0x88, 0xb9, 0xfb, 0xb7, 0xdc, 0x40, 0x6e, 0x4d, 0x16}
+)
+
+// Most boxes read at one level, guarding against corrupt sizes
+const maxBoxes = 1024
+
+// A box of the file, its payload running from Start to End
+type box struct {
+	Type  string
+	Start int64
+	End   int64
+}
+
+// A TIFF structure from a CMT box.  Its offsets are relative to the start
+// of the box's payload, which Reader takes care of.
+type Directory struct {
+	Reader *tiff.Reader
+	IFD    *tiff.IFD
+}
+
+// An embedded JPEG image
+type image struct {
+	offset int64
+	length uint32
+	width  int
+	height int
+}
+
+// A decoded CR3 file
+type File struct {
+	r io.ReaderAt
+
+	// Directories of the file; Exif, MakerNote and GPS are nil if missing
+	IFD0      *Directory
+	Exif      *Directory
+	MakerNote *Directory
+	GPS       *Directory
+
+	Make  string
+	Model string
+
+	preview   *image
+	thumbnail *image
+}
+
+// Decode the boxes of a CR3 file.  The images themselves aren't read until
+// asked for.
+func Decode(r io.ReaderAt) (*File, error) {
+	boxes, err := readBoxes(r, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) == 0 || !isFileType(r, boxes[0]) {
+		return nil, ErrFormat
+	}
+
+	f := &File{r: r}
+	for _, b := range boxes {
+		switch {
+		case b.Type == "moov":
+			if err := f.readMovie(b); err != nil {
+				return nil, err
+			}
+		case isUUID(r, b, previewUUID):
+			if f.preview, err = readPreview(r, b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f.IFD0 == nil {
+		return nil, errors.New("cr3: file has no CMT1 metadata")
+	}
+
+	if entry := f.IFD0.IFD.Find(tiff.Make); entry != nil {
+		f.Make = entry.String()
+	}
+	if entry := f.IFD0.IFD.Find(tiff.Model); entry != nil {
+		f.Model = entry.String()
+	}
+	return f, nil
+}
+
+func (f *File) readMovie(moov box) error {
+	boxes, err := readBoxes(f.r, moov.Start, moov.End)
+	if err != nil {
+		return err
+	}
+	for _, b := range boxes {
+		if !isUUID(f.r, b, canonUUID) {
+			continue
+		}
+		children, err := readBoxes(f.r, b.Start+16, b.End)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			switch child.Type {
+			case "CMT1":
+				f.IFD0, err = readDirectory(f.r, child)
+			case "CMT2":
+				f.Exif, err = readDirectory(f.r, child)
+			case "CMT3":
+				f.MakerNote, err = readDirectory(f.r, child)
+			case "CMT4":
+				f.GPS, err = readDirectory(f.r, child)
+			case "THMB":
+				// version(1) flags(3) width(2) height(2) length(4) unknown(4)
+				f.thumbnail, err = readImage(f.r, child, 4, 6, 8, 16)
+			}
+			if err != nil {
+				return errors.New(fmt.Sprintf("cr3: reading %s: %s", child.Type, err))
+			}
+		}
+	}
+	return nil
+}
+
+// The full-size JPEG preview
+func (f *File) Preview() ([]byte, error) {
+	if f.preview == nil {
+		return nil, errors.New("cr3: file has no preview")
+	}
+	return f.preview.read(f.r)
+}
+
+// Width and height of the full-size preview
+func (f *File) PreviewSize() (int, int) {
+	if f.preview == nil {
+		return 0, 0
+	}
+	return f.preview.width, f.preview.height
+}
+
+// The small JPEG thumbnail, normally 160x120
+func (f *File) Thumbnail() ([]byte, error) {
+	if f.thumbnail == nil {
+		return nil, errors.New("cr3: file has no thumbnail")
+	}
+	return f.thumbnail.read(f.r)
+}
+
+func (i *image) read(r io.ReaderAt) ([]byte, error) {
+	data := make([]byte, i.length)
+	if _, err := r.ReadAt(data, i.offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Offset and length of the full-size preview, found from the boxes ahead of
+// it alone, so r need only hold the start of the file, such as while it is
+// still being downloaded
+func PreviewRange(r io.ReaderAt) (int64, int64, error) {
+	for offset, n := int64(0), 0; n < maxBoxes; n++ {
+		b, err := readBox(r, offset, -1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if n == 0 && !isFileType(r, b) {
+			return 0, 0, ErrFormat
+		}
+		if isUUID(r, b, previewUUID) {
+			preview, err := readPreview(r, b)
+			if err != nil {
+				return 0, 0, err
+			}
+			if preview == nil {
+				break
+			}
+			return preview.offset, int64(preview.length), nil
+		}
+		if b.End < 0 {
+			break
+		}
+		offset = b.End
+	}
+	return 0, 0, errors.New("cr3: file has no preview")
+}
+
+// Read the boxes from start up to end, or to the end of the data if end is
+// negative
+func readBoxes(r io.ReaderAt, start int64, end int64) ([]box, error) {
+	var boxes []box
+	for offset := start; end < 0 || offset+8 <= end; {
+		if len(boxes) == maxBoxes {
+			return nil, errors.New("cr3: too many boxes")
+		}
+		b, err := readBox(r, offset, end)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		boxes = append(boxes, b)
+		if b.End < 0 {
+			break
+		}
+		offset = b.End
+	}
+	return boxes, nil
+}
+
+// Read the header of the box at offset in a parent ending at end, or at the
+// end of the data if end is negative, returning io.EOF if the data ends
+// there
+func readBox(r io.ReaderAt, offset int64, end int64) (box, error) {
+	header := make([]byte, 16)
+	n, err := r.ReadAt(header, offset)
+	if n < 8 {
+		if end < 0 && n == 0 && err == io.EOF {
+			return box{}, io.EOF
+		}
+		return box{}, errors.New(fmt.Sprintf("cr3: truncated box at offset %d", offset))
+	}
+
+	b := box{Type: string(header[4:8]), Start: offset + 8}
+	size := int64(binary.BigEndian.Uint32(header))
+	switch size {
+	case 0:
+		// the box runs to the end of its parent
+		b.End = end
+	case 1:
+		if n < 16 {
+			return box{}, errors.New(fmt.Sprintf("cr3: truncated box at offset %d", offset))
+		}
+		size = int64(binary.BigEndian.Uint64(header[8:]))
+		b.Start += 8
+		b.End = offset + size
+	default:
+		b.End = offset + size
+	}
+	if b.End >= 0 && (b.End < b.Start || (end >= 0 && b.End > end)) {
+		return box{}, errors.New(fmt.Sprintf("cr3: box %q at offset %d has invalid size %d", b.Type, offset, size))
+	}
+	return b, nil
+}
+
+// Whether a box is the ftyp box of a CR3 file
+func isFileType(r io.ReaderAt, b box) bool {
+	if b.Type != "ftyp" {
+		return false
+	}
+	brand := make([]byte, 4)
+	if _, err := r.ReadAt(brand, b.Start); err != nil {
+		return false
+	}
+	return string(brand) == "crx "
+}
+
+func isUUID(r io.ReaderAt, b box, uuid []byte) bool {
+	if b.Type != "uuid" {
+		return false
+	}
+	value := make([]byte, 16)
+	if _, err := r.ReadAt(value, b.Start); err != nil {
+		return false
+	}
+	return bytes.Equal(value, uuid)
+}
+
+func readDirectory(r io.ReaderAt, b box) (*Directory, error) {
+	reader, err := tiff.NewReader(io.NewSectionReader(r, b.Start, b.End-b.Start))
+	if err != nil {
+		return nil, err
+	}
+	ifd, err := reader.ReadIFD(reader.First)
+	if err != nil {
+		return nil, err
+	}
+	return &Directory{Reader: reader, IFD: ifd}, nil
+}
+
+// Read the description of the preview from the PRVW box in the uuid box
+// holding it, nil if there is none.  Only the PRVW box's header is read, and
+// the boxes ahead of it.
+func readPreview(r io.ReaderAt, b box) (*image, error) {
+	// eight bytes of unknown purpose come before the PRVW box
+	for offset, n := b.Start+16+8, 0; offset+8 <= b.End && n < maxBoxes; n++ {
+		child, err := readBox(r, offset, b.End)
+		if err != nil {
+			return nil, err
+		}
+		if child.Type == "PRVW" {
+			// unknown(4) unknown(2) width(2) height(2) unknown(2) length(4)
+			return readImage(r, child, 6, 8, 12, 16)
+		}
+		offset = child.End
+	}
+	return nil, nil
+}
+
+// Read the description of a JPEG image from the header of its box, given
+// the offsets in the payload of its width, height, length and data
+func readImage(r io.ReaderAt, b box, width, height, length, data int64) (*image, error) {
+	header := make([]byte, data)
+	if _, err := r.ReadAt(header, b.Start); err != nil {
+		return nil, err
+	}
+	i := &image{
+		offset: b.Start + data,
+		length: binary.BigEndian.Uint32(header[length:]),
+		width:  int(binary.BigEndian.Uint16(header[width:])),
+		height: int(binary.BigEndian.Uint16(header[height:])),
+	}
+	if b.End >= 0 && i.offset+int64(i.length) > b.End {
+		return nil, errors.New(fmt.Sprintf("cr3: image of %d bytes overruns its %s box", i.length, b.Type))
+	}
+	return i, nil
+}
@@ -0,0 +1,149 @@
+package cr3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+var (
+	testPreview   = []byte{0xFF, 0xD8, 0xFF, 0xD9, 'p', 'r', 'e', 'v', 'i', 'e', 'w'}
+	testThumbnail = []byte{0xFF, 0xD8, 0xFF, 0xD9, 't', 'h', 'u', 'm', 'b'}
+)
+
+func testBox(boxType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)+8))
+	copy(header[4:], boxType)
+	return append(header, data...)
+}
+
+func testTIFF(entries func(b *tifftest.Builder) []tifftest.Entry) []byte {
+	b := tifftest.New(binary.LittleEndian)
+	b.SetFirst(b.IFD(entries(b), 0))
+	return b.Bytes()
+}
+
+func testImageHeader(size int, fields map[int]uint32, widths map[int]uint16) []byte {
+	header := make([]byte, size)
+	for offset, value := range fields {
+		binary.BigEndian.PutUint32(header[offset:], value)
+	}
+	for offset, value := range widths {
+		binary.BigEndian.PutUint16(header[offset:], value)
+	}
+	return header
+}
+
+func buildTestCR3() []byte {
+	cmt1 := testTIFF(func(b *tifftest.Builder) []tifftest.Entry {
+		return []tifftest.Entry{b.ASCII(tiff.Make, "Canon"), b.ASCII(tiff.Model, "Canon EOS R5")}
+	})
+	cmt2 := testTIFF(func(b *tifftest.Builder) []tifftest.Entry {
+		return []tifftest.Entry{b.Short(tiff.ISOSpeedRatings, 100), b.Rational(tiff.ExposureTime, 1, 60)}
+	})
+	cmt3 := testTIFF(func(b *tifftest.Builder) []tifftest.Entry {
+		return []tifftest.Entry{b.ASCII(0x0095, "RF24-105mm F4 L IS USM")}
+	})
+	thumbnail := testImageHeader(16, map[int]uint32{8: uint32(len(testThumbnail))}, map[int]uint16{4: 160, 6: 120})
+	preview := testImageHeader(16, map[int]uint32{12: uint32(len(testPreview))}, map[int]uint16{6: 1620, 8: 1080})
+
+	return bytes.Join([][]byte{
+		testBox("ftyp", []byte("crx "), []byte{0, 0, 0, 1}, []byte("crx isom")),
+		testBox("moov",
+			testBox("uuid", canonUUID,
+				testBox("CNCV", []byte("CanonCR3_001/00.09.00/00.00.00")),
+				testBox("CMT1", cmt1),
+				testBox("CMT2", cmt2),
+				testBox("CMT3", cmt3),
+				testBox("THMB", thumbnail, testThumbnail),
+			),
+			testBox("trak"),
+		),
+		testBox("uuid", previewUUID, make([]byte, 8), testBox("PRVW", preview, testPreview)),
+		testBox("mdat", []byte("sensor data")),
+	}, nil)
+}
+
+func TestDecode(t *testing.T) {
+	f, err := Decode(bytes.NewReader(buildTestCR3()))
+	assert.Nil(t, err)
+	assert.Equal(t, "Canon", f.Make)
+	assert.Equal(t, "Canon EOS R5", f.Model)
+	assert.Nil(t, f.GPS)
+
+	iso, err := f.Exif.IFD.Find(tiff.ISOSpeedRatings).Uint(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(100), iso)
+	exposure, err := f.Exif.IFD.Find(tiff.ExposureTime).Float(0)
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.0/60, exposure, 0.0001)
+	assert.Equal(t, "RF24-105mm F4 L IS USM", f.MakerNote.IFD.Find(0x0095).String())
+}
+
+func TestPreviewAndThumbnail(t *testing.T) {
+	f, err := Decode(bytes.NewReader(buildTestCR3()))
+	assert.Nil(t, err)
+
+	preview, err := f.Preview()
+	assert.Nil(t, err)
+	assert.Equal(t, testPreview, preview)
+	width, height := f.PreviewSize()
+	assert.Equal(t, 1620, width)
+	assert.Equal(t, 1080, height)
+
+	thumbnail, err := f.Thumbnail()
+	assert.Nil(t, err)
+	assert.Equal(t, testThumbnail, thumbnail)
+}
+
+func TestPreviewRange(t *testing.T) {
+	data := buildTestCR3()
+	start := bytes.Index(data, testPreview)
+
+	// the boxes ahead of the preview are enough
+	offset, length, err := PreviewRange(bytes.NewReader(data[:start]))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(start), offset)
+	assert.Equal(t, testPreview, data[offset:offset+length])
+
+	_, _, err = PreviewRange(bytes.NewReader(data[:start-4]))
+	assert.NotNil(t, err)
+	_, _, err = PreviewRange(bytes.NewReader(testBox("ftyp", []byte("isom"))))
+	assert.Equal(t, ErrFormat, err)
+}
+
+func TestLargeBoxSize(t *testing.T) {
+	data := buildTestCR3()
+	// rewrite the trailing mdat box with a 64-bit size
+	mdat := bytes.LastIndex(data, []byte("mdat")) - 4
+	large := make([]byte, 16)
+	binary.BigEndian.PutUint32(large, 1)
+	copy(large[4:], "mdat")
+	binary.BigEndian.PutUint64(large[8:], uint64(16+len("sensor data")))
+	data = append(append(data[:mdat:mdat], large...), "sensor data"...)
+
+	_, err := Decode(bytes.NewReader(data))
+	assert.Nil(t, err)
+}
+
+func TestNotCR3(t *testing.T) {
+	_, err := Decode(bytes.NewReader(testBox("ftyp", []byte("isom"))))
+	assert.Equal(t, ErrFormat, err)
+	_, err = Decode(bytes.NewReader([]byte("II*\x00\x08\x00\x00\x00")))
+	assert.NotNil(t, err)
+}
+
+func TestCorruptBoxSize(t *testing.T) {
+	data := buildTestCR3()
+	moov := bytes.Index(data, []byte("moov")) - 4
+	binary.BigEndian.PutUint32(data[moov:], 4)
+
+	_, err := Decode(bytes.NewReader(data))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,272 @@
+package eos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Name of the manifest Backup keeps in the destination directory
+const BackupManifestName = "backup-manifest.json"
+
+// Record of the files a Backup has copied
+type BackupManifest struct {
+	Files []BackupEntry `json:"files"`
+}
+
+type BackupEntry struct {
+	// Path of the file on the card, and of its copy relative to the
+	// destination.  They differ when a file with the same name, such as from
+	// another card or after the file counter was reset, was already backed
+	// up.
+	Source string `json:"source"`
+	Path   string `json:"path"`
+
+	Size   int64     `json:"size"`
+	Time   time.Time `json:"time"`
+	SHA256 string    `json:"sha256"`
+	Copied time.Time `json:"copied"`
+}
+
+// Outcome of a Backup, listing paths on the card
+type BackupReport struct {
+	Copied  []string
+	Skipped []string
+	Failed  map[string]error
+
+	// Paths relative to the destination of files copied under a new name
+	// because an earlier backup already holds a different file under
+	// theirs, keyed by their path on the card
+	Renamed map[string]string
+}
+
+// Copy new files under root on the card, normally "DCIM", to the destination
+// directory, keeping the card's folder layout.  Files already in the manifest
+// with the same path, size and capture time are skipped, so an interrupted
+// backup picks up where it left off.  Files already backed up are never
+// replaced: a different file whose name is taken, such as from another card
+// or after the file counter was reset, is copied under the name with a
+// number added, so IMG_0001.CR2 becomes IMG_0001-1.CR2.  Each copy is read
+// back and checked against the checksum of the data read from the card
+// before it is recorded in the manifest.  A file that fails doesn't stop the
+// others; the error returned then summarises the failures listed in the
+// report.
+func Backup(ctx context.Context, card fs.FS, root string, destination string) (*BackupReport, error) {
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return nil, err
+	}
+	manifest, err := readBackupManifest(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BackupReport{Failed: make(map[string]error), Renamed: make(map[string]string)}
+	err = fs.WalkDir(card, root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			report.Failed[name] = err
+			return nil
+		}
+
+		// a copy lost from the destination is copied again to where it was
+		target := ""
+		if previous := manifest.find(name, info.Size(), info.ModTime()); previous != nil {
+			stat, err := os.Stat(filepath.Join(destination, filepath.FromSlash(previous.Path)))
+			if err == nil && stat.Size() == info.Size() {
+				report.Skipped = append(report.Skipped, name)
+				return nil
+			}
+			if os.IsNotExist(err) {
+				target = previous.Path
+			}
+		}
+		if target == "" {
+			target = manifest.freePath(destination, name)
+		}
+
+		sum, err := copyVerified(card, name, filepath.Join(destination, filepath.FromSlash(target)))
+		if err != nil {
+			report.Failed[name] = err
+			return nil
+		}
+		manifest.record(BackupEntry{Source: name, Path: target, Size: info.Size(), Time: info.ModTime(), SHA256: sum, Copied: time.Now().UTC()})
+		if err := writeBackupManifest(destination, manifest); err != nil {
+			return err
+		}
+		report.Copied = append(report.Copied, name)
+		if target != name {
+			report.Renamed[name] = target
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	if len(report.Failed) > 0 {
+		return report, errors.New(fmt.Sprintf("%d files failed to back up", len(report.Failed)))
+	}
+	return report, nil
+}
+
+// Find the entry for a file on the card, nil if it hasn't been backed up
+func (m *BackupManifest) find(source string, size int64, modified time.Time) *BackupEntry {
+	for i := range m.Files {
+		entry := &m.Files[i]
+		if entry.Source == source && entry.Size == size && entry.Time.Equal(modified) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Add an entry, replacing any for the same copy
+func (m *BackupManifest) record(entry BackupEntry) {
+	for i := range m.Files {
+		if m.Files[i].Path == entry.Path {
+			m.Files[i] = entry
+			return
+		}
+	}
+	m.Files = append(m.Files, entry)
+}
+
+// Path relative to the destination to copy a file from the card to: its path
+// on the card unless a file is already there or the manifest holds another
+// copy there, otherwise the first free one with a number added to its name
+func (m *BackupManifest) freePath(destination string, name string) string {
+	extension := path.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s-%d%s", base, n, extension)
+		}
+		if _, err := os.Lstat(filepath.Join(destination, filepath.FromSlash(candidate))); !os.IsNotExist(err) {
+			continue
+		}
+		taken := false
+		for _, entry := range m.Files {
+			if entry.Path == candidate {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return candidate
+		}
+	}
+}
+
+// Copy a file from the card to target by way of a temporary file, returning
+// the SHA-256 of the data once the copy on disk has been read back and found
+// to match
+func copyVerified(card fs.FS, name string, target string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+
+	source, err := card.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	partial := target + ".part"
+	file, err := os.Create(partial)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(partial)
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), source); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	written, err := fileSHA256(partial)
+	if err != nil {
+		return "", err
+	}
+	if written != sum {
+		return "", errors.New(fmt.Sprintf("Checksum of copy does not match %s", path.Base(name)))
+	}
+
+	// never replace a file, in case another one appeared there meanwhile
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		return "", errors.New(fmt.Sprintf("Not replacing existing file %s", filepath.Base(target)))
+	}
+	return sum, os.Rename(partial, target)
+}
+
+func fileSHA256(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func readBackupManifest(destination string) (*BackupManifest, error) {
+	manifest := &BackupManifest{}
+	data, err := os.ReadFile(filepath.Join(destination, BackupManifestName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(manifest); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading backup manifest: %s", err))
+	}
+	return manifest, nil
+}
+
+// Replace the manifest in one step so an interruption can't leave it half
+// written
+func writeBackupManifest(destination string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(destination, BackupManifestName)
+	if err := os.WriteFile(name+".part", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".part", name)
+}
@@ -0,0 +1,130 @@
+package eos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCard() fstest.MapFS {
+	taken := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	return fstest.MapFS{
+		"DCIM/100CANON/IMG_0001.CR2": {Data: []byte("raw one"), ModTime: taken},
+		"DCIM/100CANON/IMG_0001.JPG": {Data: []byte("jpeg one"), ModTime: taken},
+		"DCIM/101CANON/IMG_0002.CR2": {Data: []byte("raw two"), ModTime: taken.Add(time.Minute)},
+		"MISC/AUTPRINT.MRK":          {Data: []byte("print order")},
+	}
+}
+
+func TestBackup(t *testing.T) {
+	card := newTestCard()
+	destination := t.TempDir()
+
+	report, err := Backup(context.Background(), card, "DCIM", destination)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DCIM/100CANON/IMG_0001.CR2", "DCIM/100CANON/IMG_0001.JPG", "DCIM/101CANON/IMG_0002.CR2"}, report.Copied)
+	assert.Empty(t, report.Skipped)
+
+	data, err := os.ReadFile(filepath.Join(destination, "DCIM", "101CANON", "IMG_0002.CR2"))
+	assert.Nil(t, err)
+	assert.Equal(t, "raw two", string(data))
+	_, err = os.Stat(filepath.Join(destination, "MISC"))
+	assert.True(t, os.IsNotExist(err))
+
+	manifest, err := readBackupManifest(destination)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(manifest.Files))
+	entry := manifest.Files[2]
+	assert.Equal(t, "DCIM/101CANON/IMG_0002.CR2", entry.Source)
+	assert.Equal(t, "DCIM/101CANON/IMG_0002.CR2", entry.Path)
+	sum := sha256.Sum256([]byte("raw two"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), entry.SHA256)
+	assert.Equal(t, int64(7), entry.Size)
+	assert.True(t, entry.Time.Equal(card["DCIM/101CANON/IMG_0002.CR2"].ModTime))
+}
+
+func TestBackupIsIncremental(t *testing.T) {
+	card := newTestCard()
+	destination := t.TempDir()
+	_, err := Backup(context.Background(), card, "DCIM", destination)
+	assert.Nil(t, err)
+
+	// a new shot, a file reused with a different size, and a copy lost from
+	// the destination are copied, the reused file alongside the first copy
+	// rather than over it; everything else is skipped
+	card["DCIM/101CANON/IMG_0003.CR2"] = &fstest.MapFile{Data: []byte("raw three")}
+	card["DCIM/100CANON/IMG_0001.JPG"] = &fstest.MapFile{Data: []byte("a different jpeg"), ModTime: card["DCIM/100CANON/IMG_0001.JPG"].ModTime}
+	assert.Nil(t, os.Remove(filepath.Join(destination, "DCIM", "100CANON", "IMG_0001.CR2")))
+
+	report, err := Backup(context.Background(), card, "DCIM", destination)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DCIM/100CANON/IMG_0001.CR2", "DCIM/100CANON/IMG_0001.JPG", "DCIM/101CANON/IMG_0003.CR2"}, report.Copied)
+	assert.Equal(t, []string{"DCIM/101CANON/IMG_0002.CR2"}, report.Skipped)
+	assert.Equal(t, map[string]string{"DCIM/100CANON/IMG_0001.JPG": "DCIM/100CANON/IMG_0001-1.JPG"}, report.Renamed)
+
+	data, _ := os.ReadFile(filepath.Join(destination, "DCIM", "100CANON", "IMG_0001.JPG"))
+	assert.Equal(t, "jpeg one", string(data))
+	data, _ = os.ReadFile(filepath.Join(destination, "DCIM", "100CANON", "IMG_0001-1.JPG"))
+	assert.Equal(t, "a different jpeg", string(data))
+
+	manifest, _ := readBackupManifest(destination)
+	assert.Equal(t, 5, len(manifest.Files))
+
+	// both versions are now backed up, so nothing more is copied
+	report, err = Backup(context.Background(), card, "DCIM", destination)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Copied)
+}
+
+func TestBackupNameCollision(t *testing.T) {
+	destination := t.TempDir()
+	first := newTestCard()
+	_, err := Backup(context.Background(), first, "DCIM", destination)
+	assert.Nil(t, err)
+
+	// another card, or the same one after its file counter was reset, with
+	// different shots under the same names
+	second := fstest.MapFS{
+		"DCIM/100CANON/IMG_0001.CR2": {Data: []byte("another raw"), ModTime: time.Date(2015, 7, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(destination, "DCIM", "100CANON", "IMG_0001-1.CR2"), []byte("not from a card"), 0644))
+
+	report, err := Backup(context.Background(), second, "DCIM", destination)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DCIM/100CANON/IMG_0001.CR2"}, report.Copied)
+	assert.Equal(t, "DCIM/100CANON/IMG_0001-2.CR2", report.Renamed["DCIM/100CANON/IMG_0001.CR2"])
+
+	for name, want := range map[string]string{"IMG_0001.CR2": "raw one", "IMG_0001-1.CR2": "not from a card", "IMG_0001-2.CR2": "another raw"} {
+		data, err := os.ReadFile(filepath.Join(destination, "DCIM", "100CANON", name))
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(data), name)
+	}
+
+	// going back to the first card copies nothing
+	report, err = Backup(context.Background(), first, "DCIM", destination)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Copied)
+	assert.Equal(t, 3, len(report.Skipped))
+}
+
+func TestBackupCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	destination := t.TempDir()
+
+	report, err := Backup(ctx, newTestCard(), "DCIM", destination)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, report.Copied)
+}
+
+func TestBackupMissingRoot(t *testing.T) {
+	_, err := Backup(context.Background(), newTestCard(), "DCIM2", t.TempDir())
+	assert.NotNil(t, err)
+}
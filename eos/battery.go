@@ -0,0 +1,179 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+*/
+import (
+	"C"
+)
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unsafe"
+)
+
+// Wear of the battery, as reported by the camera's battery information screen
+type BatteryQuality int
+
+const (
+	BatteryQualityLow  BatteryQuality = C.kEdsBatteryQuality_Low
+	BatteryQualityHalf BatteryQuality = C.kEdsBatteryQuality_Half
+	BatteryQualityHigh BatteryQuality = C.kEdsBatteryQuality_HI
+)
+
+// Value of the battery level property while the camera runs from an AC
+// adapter or USB power
+const batteryLevelAC uint32 = C.kEdsBatteryLevel_AC
+
+// How the camera is powered
+type PowerStatus struct {
+	// Charge left in the battery as a percentage, 0 when ACPower is set.
+	// Older bodies only report steps such as 30, 50 and 80.
+	BatteryLevel int
+
+	// Whether the battery's wear is known; older bodies don't report it
+	BatteryQuality    BatteryQuality
+	HasBatteryQuality bool
+
+	// Whether the camera runs from an AC adapter or USB power
+	ACPower bool
+}
+
+// Read how the camera is powered and how much charge is left
+func (c *CameraModel) GetPowerStatus() (*PowerStatus, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+	level, err := c.getUInt32Property(C.kEdsPropID_BatteryLevel)
+	if err != nil {
+		return nil, err
+	}
+	status := &PowerStatus{}
+	if level == batteryLevelAC {
+		status.ACPower = true
+	} else {
+		status.BatteryLevel = int(level)
+	}
+	if quality, err := c.getUInt32Property(C.kEdsPropID_BatteryQuality); err == nil {
+		status.BatteryQuality, status.HasBatteryQuality = BatteryQuality(quality), true
+	}
+	return status, nil
+}
+
+// Deliver a BatteryLow event to subscribers each time the battery level falls
+// below one of the percentages given, such as 20 and then 10.  Replaces any
+// thresholds set before; none stops the events.  Levels already below a
+// threshold when it is set don't cause an event, so check GetPowerStatus
+// first.  Events are only delivered while ProcessEvents is being called.
+// Each percentage must be from 1 to 100.
+func (c *CameraModel) SetBatteryThresholds(percentages ...int) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if err := validateBatteryThresholds(percentages); err != nil {
+		return err
+	}
+	level, err := c.getUInt32Property(C.kEdsPropID_BatteryLevel)
+	if err != nil {
+		return err
+	}
+
+	id := *(*C.int)(c.eventContext)
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+	if len(percentages) == 0 {
+		delete(batteryMonitors, id)
+		return nil
+	}
+	batteryMonitors[id] = newBatteryMonitor(c.camera, level, percentages)
+	return nil
+}
+
+func validateBatteryThresholds(percentages []int) error {
+	for _, percentage := range percentages {
+		if percentage < 1 || percentage > 100 {
+			return errors.New(fmt.Sprintf("Battery threshold %d is not a percentage from 1 to 100", percentage))
+		}
+	}
+	return nil
+}
+
+// Battery thresholds of each camera with a session open, keyed like
+// eventSubscribers and guarded by eventMutex
+var batteryMonitors = make(map[C.int]*batteryMonitor)
+
+// Tracks the battery level of a camera to spot when it falls below a
+// threshold
+type batteryMonitor struct {
+	camera     *C.EdsCameraRef
+	thresholds []int
+	last       uint32
+}
+
+func newBatteryMonitor(camera *C.EdsCameraRef, level uint32, thresholds []int) *batteryMonitor {
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	return &batteryMonitor{camera: camera, thresholds: sorted, last: level}
+}
+
+// Record a new battery level, returning the lowest threshold it fell below
+// since the last one if any.  Running from AC power, or a fresh battery,
+// starts again from the new level.
+func (m *batteryMonitor) update(level uint32) (int, bool) {
+	last := m.last
+	m.last = level
+	if level == batteryLevelAC {
+		return 0, false
+	}
+	if last == batteryLevelAC {
+		last = 100
+	}
+	for _, threshold := range m.thresholds {
+		if int(level) < threshold && int(last) >= threshold {
+			return threshold, true
+		}
+	}
+	return 0, false
+}
+
+// Check the battery level of a camera after the camera reports it changed,
+// publishing a BatteryLow event if it fell below a threshold
+func checkBatteryLevel(handlerContext unsafe.Pointer) {
+	id := *(*C.int)(handlerContext)
+
+	// hold a reference so closing the session meanwhile can't free the
+	// camera while its level is read
+	eventMutex.Lock()
+	monitor := batteryMonitors[id]
+	if monitor == nil {
+		eventMutex.Unlock()
+		return
+	}
+	camera := (*C.struct___EdsObject)(unsafe.Pointer(monitor.camera))
+	C.EdsRetain(camera)
+	eventMutex.Unlock()
+
+	level, err := getUInt32Property(camera, C.kEdsPropID_BatteryLevel)
+	C.EdsRelease(camera)
+	if err != nil {
+		return
+	}
+
+	// the thresholds may have been replaced or removed meanwhile
+	eventMutex.Lock()
+	if batteryMonitors[id] != monitor {
+		eventMutex.Unlock()
+		return
+	}
+	threshold, crossed := monitor.update(level)
+	eventMutex.Unlock()
+	if crossed {
+		publish(handlerContext, Event{Type: BatteryLow, PropertyID: PropertyBatteryLevel, BatteryLevel: int(level), BatteryThreshold: threshold})
+	}
+}
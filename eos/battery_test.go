@@ -0,0 +1,52 @@
+package eos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatteryMonitor(t *testing.T) {
+	monitor := newBatteryMonitor(nil, 50, []int{10, 20})
+
+	levels := []struct {
+		level     uint32
+		threshold int
+		crossed   bool
+	}{
+		{40, 0, false},
+		{20, 0, false},
+		{19, 20, true},
+		{15, 0, false},
+		{10, 0, false},
+		{9, 10, true},
+		{batteryLevelAC, 0, false},
+		// unplugged with a fresh battery, then run down past both at once
+		{80, 0, false},
+		{5, 10, true},
+	}
+	for _, l := range levels {
+		threshold, crossed := monitor.update(l.level)
+		assert.Equal(t, l.crossed, crossed, "level %d", l.level)
+		assert.Equal(t, l.threshold, threshold, "level %d", l.level)
+	}
+
+	// already below the threshold when it was set
+	monitor = newBatteryMonitor(nil, 15, []int{20})
+	_, crossed := monitor.update(14)
+	assert.False(t, crossed)
+
+	// running from AC power when it was set
+	monitor = newBatteryMonitor(nil, batteryLevelAC, []int{20})
+	threshold, crossed := monitor.update(18)
+	assert.True(t, crossed)
+	assert.Equal(t, 20, threshold)
+}
+
+func TestValidateBatteryThresholds(t *testing.T) {
+	assert.Nil(t, validateBatteryThresholds([]int{1, 20, 100}))
+	assert.Nil(t, validateBatteryThresholds(nil))
+	for _, percentage := range []int{0, -10, 101} {
+		assert.NotNil(t, validateBatteryThresholds([]int{20, percentage}), "threshold %d", percentage)
+	}
+}
@@ -0,0 +1,141 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Anything that can take a picture and report the files it produced,
+// normally a *CameraModel
+type Capturer interface {
+	Capture(ctx context.Context, options ...TakePictureOption) ([]*DirectoryItem, error)
+}
+
+// Anything that can capture and have its exposure changed, normally a
+// *CameraModel
+type BracketCamera interface {
+	Capturer
+	PropertyController
+}
+
+// An exposure bracket for HDR, taken by changing Tv or ISO speed between
+// frames.  The frames are spread evenly around the base exposure and taken
+// from darkest to brightest.
+type Bracket struct {
+	// Property changed between frames, PropertyTv (the default) or
+	// PropertyISOSpeed
+	Property PropertyID
+
+	// Value of the property for the middle of the bracket.  Zero uses the
+	// camera's current value.
+	Base uint32
+
+	// Exposure difference between frames in stops
+	Step float64
+
+	Frames int
+
+	// Options passed to every capture
+	Options []TakePictureOption
+}
+
+// Property values for each frame of the bracket, using only values the
+// camera allows
+func (b *Bracket) values(camera PropertyController) ([]uint32, error) {
+	if b.Frames < 1 {
+		return nil, errors.New("Bracket must have at least one frame")
+	}
+	if b.Step <= 0 {
+		return nil, errors.New("Bracket step must be positive")
+	}
+	property := b.Property
+	if property == 0 {
+		property = PropertyTv
+	}
+	if property != PropertyTv && property != PropertyISOSpeed {
+		return nil, errors.New(fmt.Sprintf("Cannot bracket property 0x%x, only Tv and ISO speed", property))
+	}
+
+	base := b.Base
+	if base == 0 {
+		var err error
+		if base, err = camera.GetProperty(property); err != nil {
+			return nil, err
+		}
+	}
+	baseStops, ok := exposureStops(property, base)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Bracket base of property 0x%x must be a fixed exposure (value=0x%x)", property, base))
+	}
+
+	allowed, err := camera.GetPropertyValues(property)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]uint32, 0, b.Frames)
+	for frame := 0; frame < b.Frames; frame++ {
+		// higher stops are darker, so the darkest frame comes first
+		target := baseStops + (float64(b.Frames-1)/2-float64(frame))*b.Step
+
+		best, bestMiss := uint32(0), math.Inf(1)
+		for _, value := range allowed {
+			stops, ok := exposureStops(property, value)
+			if !ok {
+				continue
+			}
+			if miss := math.Abs(stops - target); miss < bestMiss {
+				best, bestMiss = value, miss
+			}
+		}
+		// allow for third stop values being rounded to eighths
+		if bestMiss > 1.0/6 {
+			return nil, errors.New(fmt.Sprintf("Bracket frame %d is outside the values the camera allows for property 0x%x", frame, property))
+		}
+		values = append(values, best)
+	}
+	return values, nil
+}
+
+// Take the bracket, restoring the property to its original value afterwards.
+// Returns the files of every frame in the order they were taken; they must
+// be released by the caller.
+func (b *Bracket) Capture(ctx context.Context, camera BracketCamera) ([]*DirectoryItem, error) {
+	property := b.Property
+	if property == 0 {
+		property = PropertyTv
+	}
+	original, err := camera.GetProperty(property)
+	if err != nil {
+		return nil, err
+	}
+	values, err := b.values(camera)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*DirectoryItem
+	for _, value := range values {
+		if err = camera.SetProperty(property, value); err != nil {
+			break
+		}
+		var frameItems []*DirectoryItem
+		if frameItems, err = camera.Capture(ctx, b.Options...); err != nil {
+			break
+		}
+		items = append(items, frameItems...)
+	}
+
+	if restoreErr := camera.SetProperty(property, original); err == nil {
+		err = restoreErr
+	}
+	if err != nil {
+		for _, item := range items {
+			item.Release()
+		}
+		return nil, err
+	}
+	return items, nil
+}
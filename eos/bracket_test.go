@@ -0,0 +1,91 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Camera that records the Tv and ISO speed of every capture
+type fakeBracketCamera struct {
+	*fakeExposureCamera
+	captured []map[PropertyID]uint32
+	failAt   int
+}
+
+func newFakeBracketCamera() *fakeBracketCamera {
+	return &fakeBracketCamera{fakeExposureCamera: newFakeExposureCamera(&fakeClock{}), failAt: -1}
+}
+
+func (c *fakeBracketCamera) Capture(ctx context.Context, options ...TakePictureOption) ([]*DirectoryItem, error) {
+	if len(c.captured) == c.failAt {
+		return nil, errors.New("Error when taking picture (code=2)")
+	}
+	c.captured = append(c.captured, map[PropertyID]uint32{
+		PropertyTv:       c.properties[PropertyTv],
+		PropertyISOSpeed: c.properties[PropertyISOSpeed],
+	})
+	return []*DirectoryItem{{Name: fmt.Sprintf("IMG_%04d.CR2", len(c.captured))}}, nil
+}
+
+func TestBracketTv(t *testing.T) {
+	camera := newFakeBracketCamera()
+	bracket := Bracket{Base: 0x68, Step: 1, Frames: 5}
+
+	items, err := bracket.Capture(context.Background(), camera)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(items))
+	assert.Equal(t, "IMG_0001.CR2", items[0].Name)
+
+	var tvs []uint32
+	for _, settings := range camera.captured {
+		tvs = append(tvs, settings[PropertyTv])
+	}
+	assert.Equal(t, []uint32{0x78, 0x70, 0x68, 0x60, 0x58}, tvs)
+	assert.Equal(t, uint32(0x70), camera.properties[PropertyTv])
+}
+
+func TestBracketISOFromBase(t *testing.T) {
+	camera := newFakeBracketCamera()
+	bracket := Bracket{Property: PropertyISOSpeed, Base: 0x58, Step: 2.0 / 3, Frames: 3}
+
+	_, err := bracket.Capture(context.Background(), camera)
+	assert.Nil(t, err)
+
+	var isos []uint32
+	for _, settings := range camera.captured {
+		isos = append(isos, settings[PropertyISOSpeed])
+	}
+	assert.Equal(t, []uint32{0x53, 0x58, 0x5D}, isos)
+	assert.Equal(t, uint32(0x48), camera.properties[PropertyISOSpeed])
+}
+
+func TestBracketOutsideAllowedValues(t *testing.T) {
+	camera := newFakeBracketCamera()
+	bracket := Bracket{Step: 2, Frames: 7}
+
+	_, err := bracket.Capture(context.Background(), camera)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(camera.captured))
+	assert.Equal(t, 0, camera.changes)
+}
+
+func TestBracketRestoresAfterFailure(t *testing.T) {
+	camera := newFakeBracketCamera()
+	camera.failAt = 1
+	bracket := Bracket{Step: 1, Frames: 3}
+
+	items, err := bracket.Capture(context.Background(), camera)
+	assert.NotNil(t, err)
+	assert.Nil(t, items)
+	assert.Equal(t, uint32(0x70), camera.properties[PropertyTv])
+}
+
+func TestBracketRejectsOtherProperties(t *testing.T) {
+	bracket := Bracket{Property: PropertyAv, Step: 1, Frames: 3}
+	_, err := bracket.Capture(context.Background(), newFakeBracketCamera())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,146 @@
+package eos
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Camera operations used by a CameraGroup, normally a *CameraModel
+type GroupCamera interface {
+	OpenSession() error
+	CloseSession()
+	TakePicture(options ...TakePictureOption) error
+	PressShutterButton(state ShutterButton) error
+}
+
+// Several cameras fired together, such as for photogrammetry or bullet-time
+type CameraGroup struct {
+	cameras []GroupCamera
+}
+
+// Outcome of firing one camera in a group
+type GroupResult struct {
+	// Index of the camera in the group
+	Camera int
+
+	// When the command was sent to the camera and when it returned
+	Fired time.Time
+	Done  time.Time
+
+	Err error
+}
+
+// Create a group from cameras returned by GetCameraModels, for example:
+//
+//	group := NewCameraGroup(&models[0], &models[1], &models[2])
+func NewCameraGroup(cameras ...GroupCamera) *CameraGroup {
+	return &CameraGroup{cameras: cameras}
+}
+
+// Open a session with every camera in the group.  If any fails, the sessions
+// already opened are closed again.
+func (g *CameraGroup) OpenSessions() error {
+	for i, camera := range g.cameras {
+		if err := camera.OpenSession(); err != nil {
+			for _, opened := range g.cameras[:i] {
+				opened.CloseSession()
+			}
+			return errors.New(fmt.Sprintf("Error when opening session with camera %d: %s", i, err))
+		}
+	}
+	return nil
+}
+
+// Close the sessions with every camera in the group
+func (g *CameraGroup) CloseSessions() {
+	for _, camera := range g.cameras {
+		camera.CloseSession()
+	}
+}
+
+// Take a picture on every camera at once
+func (g *CameraGroup) TakePicture(options ...TakePictureOption) ([]GroupResult, error) {
+	return g.fire(nil, func(camera GroupCamera) error {
+		return camera.TakePicture(options...)
+	})
+}
+
+// Press the shutter button halfway on every camera so each focuses and
+// meters, then once all have, press it fully on all of them at once.
+// Cameras that fail to focus are released and not fired.
+func (g *CameraGroup) FocusAndTakePicture() ([]GroupResult, error) {
+	ready, _ := g.fire(nil, func(camera GroupCamera) error {
+		return camera.PressShutterButton(ShutterPressHalfway)
+	})
+	for i, result := range ready {
+		if result.Err != nil {
+			g.cameras[i].PressShutterButton(ShutterRelease)
+		}
+	}
+
+	results, err := g.fire(ready, func(camera GroupCamera) error {
+		if err := camera.PressShutterButton(ShutterPressCompletely); err != nil {
+			camera.PressShutterButton(ShutterRelease)
+			return err
+		}
+		return camera.PressShutterButton(ShutterRelease)
+	})
+	return results, err
+}
+
+// Run the command on every camera in its own goroutine, releasing them all at
+// the same moment to keep the skew between cameras small.  Cameras with a
+// failed previous result are skipped and keep that result.
+func (g *CameraGroup) fire(previous []GroupResult, command func(camera GroupCamera) error) ([]GroupResult, error) {
+	results := make([]GroupResult, len(g.cameras))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, camera := range g.cameras {
+		if previous != nil && previous[i].Err != nil {
+			results[i] = previous[i]
+			continue
+		}
+		wg.Add(1)
+		go func(i int, camera GroupCamera) {
+			defer wg.Done()
+			<-start
+			result := GroupResult{Camera: i, Fired: time.Now()}
+			result.Err = command(camera)
+			result.Done = time.Now()
+			results[i] = result
+		}(i, camera)
+	}
+	close(start)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, errors.New(fmt.Sprintf("%d of %d cameras failed", failed, len(results)))
+	}
+	return results, nil
+}
+
+// Spread between the earliest and latest time a command was sent to the
+// cameras that succeeded
+func Skew(results []GroupResult) time.Duration {
+	var first, last time.Time
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if first.IsZero() || result.Fired.Before(first) {
+			first = result.Fired
+		}
+		if result.Fired.After(last) {
+			last = result.Fired
+		}
+	}
+	return last.Sub(first)
+}
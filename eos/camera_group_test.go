@@ -0,0 +1,125 @@
+package eos
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Camera that logs the commands it receives to a log shared by the group
+type fakeGroupCamera struct {
+	name       string
+	log        *[]string
+	mutex      *sync.Mutex
+	openErr    error
+	halfwayErr error
+	pictureErr error
+}
+
+func (c *fakeGroupCamera) record(command string) {
+	c.mutex.Lock()
+	*c.log = append(*c.log, c.name+" "+command)
+	c.mutex.Unlock()
+}
+
+func (c *fakeGroupCamera) OpenSession() error {
+	if c.openErr != nil {
+		return c.openErr
+	}
+	c.record("open")
+	return nil
+}
+
+func (c *fakeGroupCamera) CloseSession() {
+	c.record("close")
+}
+
+func (c *fakeGroupCamera) TakePicture(options ...TakePictureOption) error {
+	c.record("picture")
+	return c.pictureErr
+}
+
+func (c *fakeGroupCamera) PressShutterButton(state ShutterButton) error {
+	switch state {
+	case ShutterPressHalfway:
+		c.record("halfway")
+		return c.halfwayErr
+	case ShutterPressCompletely:
+		c.record("completely")
+	case ShutterRelease:
+		c.record("release")
+	}
+	return nil
+}
+
+func newFakeGroup(count int) ([]*fakeGroupCamera, *[]string) {
+	log := &[]string{}
+	mutex := &sync.Mutex{}
+	cameras := make([]*fakeGroupCamera, count)
+	for i := range cameras {
+		cameras[i] = &fakeGroupCamera{name: string(rune('a' + i)), log: log, mutex: mutex}
+	}
+	return cameras, log
+}
+
+func TestCameraGroupTakePicture(t *testing.T) {
+	cameras, log := newFakeGroup(3)
+	cameras[1].pictureErr = errors.New("Error when taking picture (code=2)")
+	group := NewCameraGroup(cameras[0], cameras[1], cameras[2])
+
+	results, err := group.TakePicture()
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, 3, len(*log))
+	for i, result := range results {
+		assert.Equal(t, i, result.Camera)
+		assert.False(t, result.Done.Before(result.Fired))
+	}
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, cameras[1].pictureErr, results[1].Err)
+	assert.Nil(t, results[2].Err)
+}
+
+func TestCameraGroupFocusAndTakePicture(t *testing.T) {
+	cameras, log := newFakeGroup(3)
+	cameras[2].halfwayErr = errors.New("Error when pressing shutter button (code=2)")
+	group := NewCameraGroup(cameras[0], cameras[1], cameras[2])
+
+	results, err := group.FocusAndTakePicture()
+	assert.NotNil(t, err)
+	assert.Equal(t, cameras[2].halfwayErr, results[2].Err)
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+
+	// every camera half presses before any fully presses, and the camera
+	// that failed to focus is released but never fired
+	assert.Equal(t, 8, len(*log))
+	for _, entry := range (*log)[:3] {
+		assert.Contains(t, entry, "halfway")
+	}
+	assert.Equal(t, "c release", (*log)[3])
+	assert.NotContains(t, (*log)[4:], "c completely")
+}
+
+func TestCameraGroupOpenSessionsRollsBack(t *testing.T) {
+	cameras, log := newFakeGroup(3)
+	cameras[2].openErr = errors.New("Error when opening session with camera (code=2)")
+	group := NewCameraGroup(cameras[0], cameras[1], cameras[2])
+
+	assert.NotNil(t, group.OpenSessions())
+	assert.Equal(t, []string{"a open", "b open", "a close", "b close"}, *log)
+}
+
+func TestSkew(t *testing.T) {
+	now := time.Now()
+	results := []GroupResult{
+		{Camera: 0, Fired: now.Add(2 * time.Millisecond)},
+		{Camera: 1, Fired: now},
+		{Camera: 2, Fired: now.Add(time.Second), Err: errors.New("failed")},
+		{Camera: 3, Fired: now.Add(5 * time.Millisecond)},
+	}
+	assert.Equal(t, 5*time.Millisecond, Skew(results))
+}
@@ -12,8 +12,11 @@ import (
 	"C"
 )
 import (
+	"context"
 	"errors"
 	"fmt"
+	"image"
+	"time"
 	"unsafe"
 )
 
@@ -24,11 +27,55 @@ const (
 	PC
 )
 
+type ShutterButton int
+
+const (
+	ShutterRelease ShutterButton = iota
+	ShutterPressHalfway
+	ShutterPressHalfwayNonAF
+	ShutterPressCompletely
+	ShutterPressCompletelyNonAF
+)
+
+// Size of a single lens drive step, matching the three step sizes of the
+// focus buttons in Canon's own live view software
+type FocusStep int
+
+const (
+	FocusStepSmall FocusStep = iota + 1
+	FocusStepMedium
+	FocusStepLarge
+)
+
+// How the camera picks what to focus on in LiveView.  The SDK has no way to
+// select the AF points used through the viewfinder, so focus can only be
+// placed remotely in LiveView, with SetLiveViewAFMethod and
+// SetLiveViewAFAreaPosition.
+type AFMethod int
+
+const (
+	QuickAF AFMethod = iota
+	LiveAF
+	LiveFaceAF
+	LiveMultiAF
+)
+
+type TakePictureOption int
+
+const (
+	// Fire the shutter without autofocus, leaving the lens where it is
+	SkipAutoFocus TakePictureOption = iota
+)
+
+// Returned when the camera is busy, typically still processing a previous shot
+var ErrDeviceBusy = errors.New("Camera is busy")
+
 type CameraModel struct {
 	camera         *C.EdsCameraRef
 	sessionOpen    bool
 	liveViewActive bool
 	liveViewDevice int
+	eventContext   unsafe.Pointer
 
 	szPortName          string
 	szDeviceDescription string
@@ -48,6 +95,10 @@ func (c *CameraModel) OpenSession() error {
 		return errors.New(fmt.Sprintf("Error when opening session with camera (code=%d)", eosError))
 	}
 	c.sessionOpen = true
+	if err := c.registerEventHandlers(); err != nil {
+		c.CloseSession()
+		return err
+	}
 	return nil
 }
 
@@ -57,21 +108,245 @@ func (c *CameraModel) CloseSession() {
 		return
 	}
 	c.sessionOpen = false
+	c.unregisterEventHandlers()
 	C.EdsCloseSession((*C.struct___EdsObject)(unsafe.Pointer(&c.camera)))
 }
 
-// Take a picture
-func (c *CameraModel) TakePicture() error {
+// Take a picture.  Passing SkipAutoFocus presses the shutter button fully
+// without autofocus instead of issuing the TakePicture command, which fails
+// when focus cannot be achieved.
+func (c *CameraModel) TakePicture(options ...TakePictureOption) (err error) {
 	if c.sessionOpen == false {
 		return errors.New("Session is not open, must call OpenSession first")
 	}
+	for _, option := range options {
+		if option == SkipAutoFocus {
+			if err := c.PressShutterButton(ShutterPressCompletelyNonAF); err != nil {
+				return err
+			}
+			defer func() {
+				if releaseErr := c.PressShutterButton(ShutterRelease); err == nil {
+					err = releaseErr
+				}
+			}()
+			return nil
+		}
+	}
 	eosError := C.EdsSendCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraCommand_TakePicture, 0)
+	if eosError == C.EDS_ERR_DEVICE_BUSY {
+		return ErrDeviceBusy
+	}
 	if eosError != C.EDS_ERR_OK {
 		return errors.New(fmt.Sprintf("Error when taking picture (code=%d)", eosError))
 	}
 	return nil
 }
 
+// Press or release the shutter button.  A press must be followed by
+// ShutterRelease before the button can be pressed again.
+func (c *CameraModel) PressShutterButton(state ShutterButton) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+
+	var param C.EdsInt32
+	switch state {
+	case ShutterRelease:
+		param = C.kEdsCameraCommand_ShutterButton_OFF
+	case ShutterPressHalfway:
+		param = C.kEdsCameraCommand_ShutterButton_Halfway
+	case ShutterPressHalfwayNonAF:
+		param = C.kEdsCameraCommand_ShutterButton_Halfway_NonAF
+	case ShutterPressCompletely:
+		param = C.kEdsCameraCommand_ShutterButton_Completely
+	case ShutterPressCompletelyNonAF:
+		param = C.kEdsCameraCommand_ShutterButton_Completely_NonAF
+	default:
+		return errors.New("Unrecognized shutter button state supplied")
+	}
+
+	eosError := C.EdsSendCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraCommand_PressShutterButton, param)
+	if eosError == C.EDS_ERR_DEVICE_BUSY {
+		return ErrDeviceBusy
+	}
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when pressing shutter button (code=%d)", eosError))
+	}
+	return nil
+}
+
+// Hold the shutter open in bulb mode for the given duration, returning the
+// measured length of the exposure.  The camera must be in Bulb mode, or in M
+// mode with the shutter speed set to Bulb.  Cancelling the context ends the
+// exposure early.
+func (c *CameraModel) BulbExposure(ctx context.Context, duration time.Duration) (exposure time.Duration, err error) {
+	if c.sessionOpen == false {
+		return 0, errors.New("Session is not open, must call OpenSession first")
+	}
+	if duration <= 0 {
+		return 0, errors.New("Bulb exposure duration must be positive")
+	}
+
+	aeMode, err := c.getUInt32Property(C.kEdsPropID_AEMode)
+	if err != nil {
+		return 0, err
+	}
+	if aeMode == aeModeManual {
+		tv, err := c.getUInt32Property(C.kEdsPropID_Tv)
+		if err != nil {
+			return 0, err
+		}
+		if tv != tvBulb {
+			return 0, errors.New("Shutter speed must be set to Bulb for a bulb exposure")
+		}
+	} else if aeMode != aeModeBulb {
+		return 0, errors.New(fmt.Sprintf("Camera must be in Bulb or M mode for a bulb exposure (mode=%d)", aeMode))
+	}
+
+	// lock the camera UI so the dials can't interfere with the exposure
+	eosError := C.EdsSendStatusCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraStatusCommand_UILock, 0)
+	if eosError != C.EDS_ERR_OK {
+		return 0, errors.New(fmt.Sprintf("Error locking camera UI for bulb exposure (code=%d)", eosError))
+	}
+	defer C.EdsSendStatusCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraStatusCommand_UIUnLock, 0)
+
+	if err := c.PressShutterButton(ShutterPressCompletelyNonAF); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+
+	// the exposure lasts until the button is released, however this returns
+	defer func() {
+		if releaseErr := c.PressShutterButton(ShutterRelease); releaseErr != nil {
+			err = releaseErr
+		}
+		exposure = time.Since(start)
+	}()
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return 0, ctx.Err()
+}
+
+// Drive the lens focus one step closer.  LiveView must be active.
+func (c *CameraModel) DriveLensNear(step FocusStep) error {
+	switch step {
+	case FocusStepSmall:
+		return c.driveLens(C.kEdsEvfDriveLens_Near1)
+	case FocusStepMedium:
+		return c.driveLens(C.kEdsEvfDriveLens_Near2)
+	case FocusStepLarge:
+		return c.driveLens(C.kEdsEvfDriveLens_Near3)
+	}
+	return errors.New("Unrecognized focus step supplied")
+}
+
+// Drive the lens focus one step further away.  LiveView must be active.
+func (c *CameraModel) DriveLensFar(step FocusStep) error {
+	switch step {
+	case FocusStepSmall:
+		return c.driveLens(C.kEdsEvfDriveLens_Far1)
+	case FocusStepMedium:
+		return c.driveLens(C.kEdsEvfDriveLens_Far2)
+	case FocusStepLarge:
+		return c.driveLens(C.kEdsEvfDriveLens_Far3)
+	}
+	return errors.New("Unrecognized focus step supplied")
+}
+
+// Autofocus on the AF area in LiveView
+func (c *CameraModel) DoAF() error {
+	return c.evfAF(C.kEdsCameraCommand_EvfAf_ON)
+}
+
+// Stop an autofocus started with DoAF
+func (c *CameraModel) CancelAF() error {
+	return c.evfAF(C.kEdsCameraCommand_EvfAf_OFF)
+}
+
+func (c *CameraModel) evfAF(param C.EdsInt32) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false {
+		return errors.New("LiveView must be active to autofocus")
+	}
+	eosError := C.EdsSendCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraCommand_DoEvfAf, param)
+	if eosError == C.EDS_ERR_DEVICE_BUSY {
+		return ErrDeviceBusy
+	}
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when autofocusing (code=%d)", eosError))
+	}
+	return nil
+}
+
+// Select how focus points are chosen in LiveView.  This doesn't change the AF
+// area mode used through the viewfinder.
+func (c *CameraModel) SetLiveViewAFMethod(method AFMethod) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+
+	var mode uint32
+	switch method {
+	case QuickAF:
+		mode = C.Evf_AFMode_Quick
+	case LiveAF:
+		mode = C.Evf_AFMode_Live
+	case LiveFaceAF:
+		mode = C.Evf_AFMode_LiveFace
+	case LiveMultiAF:
+		mode = C.Evf_AFMode_LiveMulti
+	default:
+		return errors.New("Unrecognized AF method supplied")
+	}
+	return c.setUInt32Property(C.kEdsPropID_Evf_AFMode, mode)
+}
+
+// Get the position of the AF area in LiveView, in the coordinates of the
+// full sensor image
+func (c *CameraModel) GetLiveViewAFAreaPosition() (image.Point, error) {
+	if c.sessionOpen == false {
+		return image.Point{}, errors.New("Session is not open, must call OpenSession first")
+	}
+	return c.getPointProperty(C.kEdsPropID_Evf_ZoomPosition)
+}
+
+// Move the AF area in LiveView so its top left corner is at the given
+// position, in the coordinates of the full sensor image.  This is the same
+// area that is magnified, and doesn't select an AF point for the viewfinder.
+func (c *CameraModel) SetLiveViewAFAreaPosition(position image.Point) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false {
+		return errors.New("LiveView must be active to move the AF area")
+	}
+	return c.setPointProperty(C.kEdsPropID_Evf_ZoomPosition, position)
+}
+
+func (c *CameraModel) driveLens(param C.EdsInt32) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false {
+		return errors.New("LiveView must be active to drive the lens")
+	}
+	eosError := C.EdsSendCommand((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.kEdsCameraCommand_DriveLensEvf, param)
+	if eosError == C.EDS_ERR_DEVICE_BUSY {
+		return ErrDeviceBusy
+	}
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when driving lens (code=%d)", eosError))
+	}
+	return nil
+}
+
 // Start LiveView on the device configured with SetLiveViewOutputDevice
 func (c *CameraModel) StartLiveView() error {
 	if c.sessionOpen == false {
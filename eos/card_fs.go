@@ -0,0 +1,283 @@
+package eos
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Read-only file system over the files and folders of a camera volume, for
+// use with fs.WalkDir, http.FS and the rest of the standard library.  Files
+// are downloaded from the camera in full on the first read.
+type CardFS struct {
+	volume *Volume
+
+	// the SDK is called from one goroutine at a time
+	mutex sync.Mutex
+}
+
+var (
+	_ fs.FS        = (*CardFS)(nil)
+	_ fs.ReadDirFS = (*CardFS)(nil)
+	_ fs.StatFS    = (*CardFS)(nil)
+)
+
+// Create a file system over the volume.  The volume must stay open for as
+// long as the file system is used.
+func NewCardFS(volume *Volume) *CardFS {
+	return &CardFS{volume: volume}
+}
+
+// Open the named file or folder, with a path such as "DCIM/100CANON".  The
+// returned file must be closed to release its reference to the camera.
+func (c *CardFS) Open(name string) (fs.File, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &cardDir{fs: c, info: rootInfo{}}, nil
+	}
+
+	item, err := c.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if item.IsFolder {
+		return &cardDir{fs: c, item: item, info: itemInfo{item}}, nil
+	}
+	return &cardFile{fs: c, item: item}, nil
+}
+
+// Read the named folder, returning its entries sorted by name
+func (c *CardFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	file, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dir, ok := file.(*cardDir)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return dir.ReadDir(-1)
+}
+
+// Describe the named file or folder
+func (c *CardFS) Stat(name string) (fs.FileInfo, error) {
+	file, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// Find the item at the path by descending from the top of the volume
+func (c *CardFS) lookup(name string) (*DirectoryItem, error) {
+	items, err := c.volume.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		var found *DirectoryItem
+		for _, item := range items {
+			if item.Name == part && found == nil {
+				found = item
+			} else {
+				item.Release()
+			}
+		}
+		if found == nil {
+			return nil, fs.ErrNotExist
+		}
+		if i == len(parts)-1 {
+			return found, nil
+		}
+		if !found.IsFolder {
+			found.Release()
+			return nil, fs.ErrNotExist
+		}
+
+		items, err = found.Children()
+		found.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// fs.FileInfo and fs.DirEntry for a directory item
+type itemInfo struct {
+	item *DirectoryItem
+}
+
+func (i itemInfo) Name() string       { return i.item.Name }
+func (i itemInfo) Size() int64        { return int64(i.item.Size) }
+func (i itemInfo) ModTime() time.Time { return i.item.Time }
+func (i itemInfo) IsDir() bool        { return i.item.IsFolder }
+func (i itemInfo) Sys() interface{}   { return nil }
+
+func (i itemInfo) Mode() fs.FileMode {
+	switch {
+	case i.item.IsFolder:
+		return fs.ModeDir | 0555
+	case i.item.Attributes&ReadOnly != 0:
+		return 0444
+	}
+	return 0644
+}
+
+func (i itemInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i itemInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// fs.FileInfo for the top of the volume
+type rootInfo struct{}
+
+func (rootInfo) Name() string       { return "." }
+func (rootInfo) Size() int64        { return 0 }
+func (rootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (rootInfo) ModTime() time.Time { return time.Time{} }
+func (rootInfo) IsDir() bool        { return true }
+func (rootInfo) Sys() interface{}   { return nil }
+
+// An open file, downloaded on the first read
+type cardFile struct {
+	fs     *CardFS
+	item   *DirectoryItem
+	reader *bytes.Reader
+}
+
+func (f *cardFile) Stat() (fs.FileInfo, error) {
+	return itemInfo{f.item}, nil
+}
+
+func (f *cardFile) download() error {
+	if f.reader != nil {
+		return nil
+	}
+	if f.item.ref == nil {
+		return fs.ErrClosed
+	}
+
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	var buffer bytes.Buffer
+	if err := f.item.Download(&buffer); err != nil {
+		return err
+	}
+	f.reader = bytes.NewReader(buffer.Bytes())
+	return nil
+}
+
+func (f *cardFile) Read(p []byte) (int, error) {
+	if err := f.download(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.item.Name, Err: err}
+	}
+	return f.reader.Read(p)
+}
+
+func (f *cardFile) ReadAt(p []byte, offset int64) (int, error) {
+	if err := f.download(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.item.Name, Err: err}
+	}
+	return f.reader.ReadAt(p, offset)
+}
+
+func (f *cardFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.download(); err != nil {
+		return 0, &fs.PathError{Op: "seek", Path: f.item.Name, Err: err}
+	}
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *cardFile) Close() error {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	f.item.Release()
+	return nil
+}
+
+// An open folder, or the top of the volume when item is nil
+type cardDir struct {
+	fs      *CardFS
+	item    *DirectoryItem
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *cardDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *cardDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *cardDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		if err := d.load(); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.info.Name(), Err: err}
+		}
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// List the folder's children.  Only their details are kept, so their
+// references are released straight away.
+func (d *cardDir) load() error {
+	d.fs.mutex.Lock()
+	defer d.fs.mutex.Unlock()
+
+	var items []*DirectoryItem
+	var err error
+	if d.item == nil {
+		items, err = d.fs.volume.Children()
+	} else {
+		items, err = d.item.Children()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Release()
+		d.entries = append(d.entries, itemInfo{item})
+	}
+	return nil
+}
+
+func (d *cardDir) Close() error {
+	if d.item != nil {
+		d.fs.mutex.Lock()
+		defer d.fs.mutex.Unlock()
+		d.item.Release()
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package eos
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardFSItemInfo(t *testing.T) {
+	taken := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	file := itemInfo{&DirectoryItem{Name: "IMG_0001.CR2", Size: 25 << 20, Time: taken}}
+	assert.Equal(t, "IMG_0001.CR2", file.Name())
+	assert.Equal(t, int64(25<<20), file.Size())
+	assert.Equal(t, taken, file.ModTime())
+	assert.Equal(t, fs.FileMode(0644), file.Mode())
+	assert.False(t, file.IsDir())
+
+	protected := itemInfo{&DirectoryItem{Name: "IMG_0002.CR2", Attributes: ReadOnly | Archive}}
+	assert.Equal(t, fs.FileMode(0444), protected.Mode())
+
+	folder := itemInfo{&DirectoryItem{Name: "100CANON", IsFolder: true}}
+	assert.True(t, folder.IsDir())
+	assert.Equal(t, fs.ModeDir, folder.Type())
+	info, err := folder.Info()
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestCardFSRejectsInvalidPaths(t *testing.T) {
+	card := NewCardFS(&Volume{})
+	_, err := card.Open("/DCIM")
+	assert.NotNil(t, err)
+	_, err = card.Open("DCIM/../MISC")
+	assert.NotNil(t, err)
+
+	root, err := card.Stat(".")
+	assert.Nil(t, err)
+	assert.True(t, root.IsDir())
+}
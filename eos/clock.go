@@ -0,0 +1,151 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+*/
+import (
+	"C"
+)
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// Properties holding the camera's clock
+const (
+	propertyDateTime   PropertyID = C.kEdsPropID_DateTime
+	propertyUTCTime    PropertyID = C.kEdsPropID_UTCTime
+	propertyTimeZone   PropertyID = C.kEdsPropID_TimeZone
+	propertySummerTime PropertyID = C.kEdsPropID_SummerTimeSetting
+)
+
+// Outcome of setting a camera's clock
+type ClockSync struct {
+	// The camera's clock before it was set, and the host time it was set to.
+	// Bodies without a UTC clock keep no time zone, so their clock is read as
+	// a time in the host time's location; TimeZoneSet is false for them.
+	CameraTime time.Time
+	HostTime   time.Time
+
+	// How far the camera's clock was ahead of the host's, negative if it was
+	// behind.  Camera clocks only count whole seconds, so this is rounded to
+	// the nearest second.
+	Drift time.Duration
+
+	// Whether the camera's time zone and summer time setting were set too,
+	// which only bodies with a UTC clock support
+	TimeZoneSet bool
+}
+
+// Clock properties of a camera, which CameraModel provides
+type clockCamera interface {
+	GetProperty(propertyID PropertyID) (uint32, error)
+	SetProperty(propertyID PropertyID, value uint32) error
+	hasProperty(propertyID PropertyID) bool
+	getTimeProperty(propertyID PropertyID, location *time.Location) (time.Time, error)
+	setTimeProperty(propertyID PropertyID, value time.Time) error
+}
+
+// Set the camera's clock to the host time given, normally time.Now().  Bodies
+// with a UTC clock also get the time zone and summer time setting of the
+// time's location; older bodies only keep local time, so are set to the time
+// in that location, and their clock is taken to have been in it too.
+func (c *CameraModel) SyncClock(now time.Time) (*ClockSync, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+	return syncClock(c, now)
+}
+
+func syncClock(camera clockCamera, now time.Time) (*ClockSync, error) {
+	result := &ClockSync{HostTime: now}
+	if camera.hasProperty(propertyUTCTime) {
+		cameraTime, err := camera.getTimeProperty(propertyUTCTime, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		result.CameraTime = cameraTime.In(now.Location())
+
+		zone, err := camera.GetProperty(propertyTimeZone)
+		if err != nil {
+			return nil, err
+		}
+		offset, summerTime := cameraTimeZone(now)
+		if err := camera.SetProperty(propertyTimeZone, zone&0xFFFF0000|uint32(uint16(offset))); err != nil {
+			return nil, err
+		}
+		if err := camera.SetProperty(propertySummerTime, summerTime); err != nil {
+			return nil, err
+		}
+		if err := camera.setTimeProperty(propertyUTCTime, now.UTC()); err != nil {
+			return nil, err
+		}
+		result.TimeZoneSet = true
+	} else {
+		cameraTime, err := camera.getTimeProperty(propertyDateTime, now.Location())
+		if err != nil {
+			return nil, err
+		}
+		result.CameraTime = cameraTime
+		if err := camera.setTimeProperty(propertyDateTime, now); err != nil {
+			return nil, err
+		}
+	}
+	result.Drift = result.CameraTime.Sub(now).Round(time.Second)
+	return result, nil
+}
+
+// Offset from UTC in minutes of the standard time of a time's location, and
+// whether summer time is in effect, as the camera's time zone and summer time
+// settings take them.  The camera adds an hour itself during summer time.
+func cameraTimeZone(now time.Time) (int16, uint32) {
+	_, offset := now.Zone()
+	if now.IsDST() {
+		return int16(offset/60 - 60), 1
+	}
+	return int16(offset / 60), 0
+}
+
+// Whether the camera has a property, which bodies that don't support it fail
+// to give the size of
+func (c *CameraModel) hasProperty(propertyID PropertyID) bool {
+	var dataType C.EdsDataType
+	var size C.EdsUInt32
+	eosError := C.EdsGetPropertySize((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.EdsPropertyID(propertyID), 0, &dataType, &size)
+	return eosError == C.EDS_ERR_OK && size > 0
+}
+
+// Read a date and time property, taking it to be in the given location
+func (c *CameraModel) getTimeProperty(propertyID PropertyID, location *time.Location) (time.Time, error) {
+	var value C.EdsTime
+	eosError := C.EdsGetPropertyData((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.EdsPropertyID(propertyID), 0, (C.EdsUInt32)(unsafe.Sizeof(value)), unsafe.Pointer(&value))
+	if eosError != C.EDS_ERR_OK {
+		return time.Time{}, errors.New(fmt.Sprintf("Error getting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return time.Date(int(value.year), time.Month(value.month), int(value.day), int(value.hour), int(value.minute), int(value.second), int(value.milliseconds)*int(time.Millisecond), location), nil
+}
+
+// Write a date and time property as the time in its own location
+func (c *CameraModel) setTimeProperty(propertyID PropertyID, value time.Time) error {
+	data := C.EdsTime{
+		year:         C.EdsUInt32(value.Year()),
+		month:        C.EdsUInt32(value.Month()),
+		day:          C.EdsUInt32(value.Day()),
+		hour:         C.EdsUInt32(value.Hour()),
+		minute:       C.EdsUInt32(value.Minute()),
+		second:       C.EdsUInt32(value.Second()),
+		milliseconds: 0,
+	}
+	eosError := C.EdsSetPropertyData((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.EdsPropertyID(propertyID), 0, (C.EdsUInt32)(unsafe.Sizeof(data)), unsafe.Pointer(&data))
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error setting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return nil
+}
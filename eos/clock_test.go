@@ -0,0 +1,107 @@
+package eos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCameraTimeZone(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	assert.Nil(t, err)
+
+	// standard time in June, summer time in January
+	offset, summerTime := cameraTimeZone(time.Date(2015, 6, 1, 12, 0, 0, 0, sydney))
+	assert.Equal(t, int16(600), offset)
+	assert.Equal(t, uint32(0), summerTime)
+	offset, summerTime = cameraTimeZone(time.Date(2015, 1, 1, 12, 0, 0, 0, sydney))
+	assert.Equal(t, int16(600), offset)
+	assert.Equal(t, uint32(1), summerTime)
+
+	offset, _ = cameraTimeZone(time.Date(2015, 6, 1, 12, 0, 0, 0, time.FixedZone("", -(3*3600+30*60))))
+	assert.Equal(t, int16(-210), offset)
+}
+
+// Camera clock kept as the time its fields show, as the SDK reports it
+type fakeClockCamera struct {
+	properties map[PropertyID]uint32
+	clocks     map[PropertyID]time.Time
+}
+
+func (c *fakeClockCamera) GetProperty(propertyID PropertyID) (uint32, error) {
+	value, ok := c.properties[propertyID]
+	if !ok {
+		return 0, errors.New("Error getting property (code=80)")
+	}
+	return value, nil
+}
+
+func (c *fakeClockCamera) SetProperty(propertyID PropertyID, value uint32) error {
+	if _, ok := c.properties[propertyID]; !ok {
+		return errors.New("Error setting property (code=80)")
+	}
+	c.properties[propertyID] = value
+	return nil
+}
+
+func (c *fakeClockCamera) hasProperty(propertyID PropertyID) bool {
+	_, ok := c.clocks[propertyID]
+	return ok
+}
+
+func (c *fakeClockCamera) getTimeProperty(propertyID PropertyID, location *time.Location) (time.Time, error) {
+	clock, ok := c.clocks[propertyID]
+	if !ok {
+		return time.Time{}, errors.New("Error getting property (code=80)")
+	}
+	return time.Date(clock.Year(), clock.Month(), clock.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, location), nil
+}
+
+func (c *fakeClockCamera) setTimeProperty(propertyID PropertyID, value time.Time) error {
+	// only whole seconds are written
+	c.clocks[propertyID] = time.Date(value.Year(), value.Month(), value.Day(), value.Hour(), value.Minute(), value.Second(), 0, time.UTC)
+	return nil
+}
+
+func TestSyncClockUTC(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	assert.Nil(t, err)
+	camera := &fakeClockCamera{
+		properties: map[PropertyID]uint32{propertyTimeZone: 0x00020000, propertySummerTime: 1},
+		clocks:     map[PropertyID]time.Time{propertyUTCTime: time.Date(2015, 6, 1, 2, 0, 3, 0, time.UTC)},
+	}
+	now := time.Date(2015, 6, 1, 12, 0, 0, 400*int(time.Millisecond), sydney)
+
+	result, err := syncClock(camera, now)
+	assert.Nil(t, err)
+	assert.True(t, result.TimeZoneSet)
+	assert.True(t, result.CameraTime.Equal(time.Date(2015, 6, 1, 2, 0, 3, 0, time.UTC)))
+	assert.Equal(t, 3*time.Second, result.Drift)
+
+	// the city part of the time zone setting is kept
+	assert.Equal(t, uint32(0x00020000|600), camera.properties[propertyTimeZone])
+	assert.Equal(t, uint32(0), camera.properties[propertySummerTime])
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 0, 0, 0, time.UTC), camera.clocks[propertyUTCTime])
+	_, local := camera.clocks[propertyDateTime]
+	assert.False(t, local)
+}
+
+func TestSyncClockLocalTime(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	assert.Nil(t, err)
+	camera := &fakeClockCamera{
+		properties: map[PropertyID]uint32{},
+		clocks:     map[PropertyID]time.Time{propertyDateTime: time.Date(2015, 6, 1, 11, 59, 58, 0, time.UTC)},
+	}
+	now := time.Date(2015, 6, 1, 12, 0, 0, 400*int(time.Millisecond), sydney)
+
+	// the camera's clock is taken to be in the host's location
+	result, err := syncClock(camera, now)
+	assert.Nil(t, err)
+	assert.False(t, result.TimeZoneSet)
+	assert.Equal(t, time.Date(2015, 6, 1, 11, 59, 58, 0, sydney), result.CameraTime)
+	assert.Equal(t, -2*time.Second, result.Drift)
+	assert.Equal(t, time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC), camera.clocks[propertyDateTime])
+}
@@ -1,7 +1,13 @@
 package eos
 
 import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"io/fs"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -85,3 +91,390 @@ func TestLiveViewPC(t *testing.T) {
 	assert.Nil(t, camera.ToggleLiveView())
 	time.Sleep(1 * time.Second)
 }
+
+// At least one camera must be connected in order to run successfully.
+func TestTakePictureSkipAutoFocus(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+	assert.Nil(t, camera.TakePicture(SkipAutoFocus))
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestPressShutterButton(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	assert.Nil(t, camera.PressShutterButton(ShutterPressHalfway))
+	assert.Nil(t, camera.PressShutterButton(ShutterPressCompletely))
+	assert.Nil(t, camera.PressShutterButton(ShutterRelease))
+	assert.NotNil(t, camera.PressShutterButton(ShutterButton(99)))
+}
+
+// At least one camera must be connected and set to Bulb mode in order to run
+// successfully.
+func TestBulbExposure(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	_, err := camera.BulbExposure(context.Background(), 0)
+	assert.NotNil(t, err)
+
+	exposure, err := camera.BulbExposure(context.Background(), 2*time.Second)
+	assert.Nil(t, err)
+	assert.True(t, exposure >= 2*time.Second)
+	assert.True(t, exposure < 3*time.Second)
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestCapture(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	items, err := camera.Capture(context.Background())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, items)
+	for _, item := range items {
+		assert.NotEqual(t, "", item.Name)
+		item.Release()
+	}
+}
+
+// At least one camera with an AF lens must be connected in order to run
+// successfully.
+func TestLiveViewFocus(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	assert.NotNil(t, camera.DriveLensNear(FocusStepSmall))
+	assert.Nil(t, camera.SetLiveViewOutputDevice(PC))
+	assert.Nil(t, camera.StartLiveView())
+	defer camera.StopLiveView()
+	time.Sleep(1 * time.Second)
+
+	assert.Nil(t, camera.SetLiveViewAFMethod(LiveAF))
+	assert.Nil(t, camera.SetLiveViewAFAreaPosition(image.Pt(2000, 1500)))
+	position, err := camera.GetLiveViewAFAreaPosition()
+	assert.Nil(t, err)
+	assert.Equal(t, image.Pt(2000, 1500), position)
+
+	assert.Nil(t, camera.DoAF())
+	time.Sleep(1 * time.Second)
+	assert.Nil(t, camera.CancelAF())
+	assert.Nil(t, camera.DriveLensNear(FocusStepMedium))
+	assert.Nil(t, camera.DriveLensFar(FocusStepMedium))
+	assert.NotNil(t, camera.DriveLensFar(FocusStep(0)))
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestLiveViewZoom(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	assert.Nil(t, camera.SetLiveViewOutputDevice(PC))
+	assert.Nil(t, camera.StartLiveView())
+	defer camera.StopLiveView()
+	time.Sleep(1 * time.Second)
+
+	assert.Nil(t, camera.SetLiveViewZoom(Zoom5x))
+	assert.Nil(t, camera.SetLiveViewZoomPosition(image.Pt(1000, 800)))
+	assert.NotNil(t, camera.SetLiveViewZoom(LiveViewZoom(3)))
+	time.Sleep(500 * time.Millisecond)
+
+	frame, err := camera.GetLiveViewFrame()
+	assert.Nil(t, err)
+	assert.Equal(t, Zoom5x, frame.Zoom)
+	assert.Equal(t, image.Pt(1000, 800), frame.ZoomPosition)
+	assert.NotEmpty(t, frame.JPEG)
+
+	zoom, err := camera.GetLiveViewZoom()
+	assert.Nil(t, err)
+	assert.Equal(t, Zoom5x, zoom)
+	assert.Nil(t, camera.SetLiveViewZoom(ZoomFit))
+}
+
+// At least one camera must be connected and set to movie mode in order to run
+// successfully.
+func TestMovieRecording(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	assert.NotNil(t, camera.StartMovieRecording())
+	assert.Nil(t, camera.SetLiveViewOutputDevice(TFT))
+	assert.Nil(t, camera.StartLiveView())
+	defer camera.StopLiveView()
+	time.Sleep(1 * time.Second)
+
+	events, err := camera.Subscribe()
+	assert.Nil(t, err)
+	defer camera.Unsubscribe(events)
+
+	assert.Nil(t, camera.StartMovieRecording())
+	recording, err := camera.IsRecordingMovie()
+	assert.Nil(t, err)
+	assert.True(t, recording)
+	time.Sleep(3 * time.Second)
+	assert.Nil(t, camera.StopMovieRecording())
+	assert.NotNil(t, camera.StopMovieRecording())
+
+	var movie *DirectoryItem
+	for i := 0; i < 100 && movie == nil; i++ {
+		e.ProcessEvents()
+		select {
+		case event := <-events:
+			if event.Type == MovieCreated {
+				movie = event.Item
+			} else if event.Item != nil {
+				event.Item.Release()
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	assert.NotNil(t, movie)
+	if movie != nil {
+		assert.True(t, movie.IsMovie())
+		movie.Release()
+	}
+}
+
+// At least one camera with a memory card must be connected in order to run
+// successfully.
+func TestWalkVolumes(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	volumes, err := camera.GetVolumes()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, volumes)
+	volume := volumes[0]
+	defer volume.Release()
+	assert.True(t, volume.Capacity > 0)
+	assert.True(t, volume.FreeSpace <= volume.Capacity)
+
+	var paths []string
+	err = Walk(volume, func(path string, item *DirectoryItem, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, paths, "DCIM")
+}
+
+// At least one camera with a memory card holding pictures must be connected
+// in order to run successfully.
+func TestCardFS(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	volumes, err := camera.GetVolumes()
+	assert.Nil(t, err)
+	defer releaseVolumes(volumes)
+
+	card := NewCardFS(volumes[0])
+	assert.Nil(t, fstest.TestFS(card, "DCIM"))
+
+	entries, err := card.ReadDir("DCIM")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, entries)
+	_, err = card.Stat("DCIM/missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+// At least one camera with a writable memory card must be connected in order
+// to run successfully.  The card is not formatted.
+func TestCardManagement(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	items, err := camera.Capture(context.Background())
+	assert.Nil(t, err)
+	item := items[0]
+	for _, other := range items[1:] {
+		other.Release()
+	}
+
+	assert.Nil(t, item.SetProtected(true))
+	assert.Equal(t, ReadOnly, item.Attributes&ReadOnly)
+	assert.NotNil(t, item.Delete())
+	assert.Nil(t, item.SetProtected(false))
+	assert.Nil(t, item.Delete())
+
+	volumes, err := camera.GetVolumes()
+	assert.Nil(t, err)
+	defer releaseVolumes(volumes)
+	assert.Equal(t, ErrFormatNotConfirmed, volumes[0].Format("format"))
+	confirmation, err := volumes[0].FormatConfirmation()
+	assert.Nil(t, err)
+	assert.NotEqual(t, confirmation, "")
+	assert.Equal(t, ErrFormatNotConfirmed, volumes[1%len(volumes)].Format(confirmation+"x"))
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestDownloadThumbnailAndPreview(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	items, err := camera.Capture(context.Background())
+	assert.Nil(t, err)
+	defer releaseItems(items)
+
+	for _, item := range items {
+		thumbnail, err := item.DownloadThumbnail()
+		assert.Nil(t, err)
+		assert.NotEmpty(t, thumbnail)
+
+		preview, err := item.DownloadPreview()
+		assert.Nil(t, err)
+		assert.True(t, len(preview) > len(thumbnail))
+		assert.True(t, uint64(len(preview)) <= item.Size)
+	}
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestDownloadExif(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	items, err := camera.Capture(context.Background())
+	assert.Nil(t, err)
+	defer releaseItems(items)
+
+	for _, item := range items {
+		assert.Nil(t, item.Download(io.Discard))
+		if item.isJPEG() {
+			assert.NotNil(t, item.Exif)
+			assert.True(t, item.Exif.ISO > 0)
+			assert.True(t, item.Exif.ExposureTime > 0)
+		} else {
+			assert.Nil(t, item.Exif)
+		}
+	}
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestSyncClock(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	_, err := camera.SyncClock(time.Now())
+	assert.Nil(t, err)
+
+	// once set, the camera is within a second or two of the host
+	result, err := camera.SyncClock(time.Now())
+	assert.Nil(t, err)
+	assert.True(t, result.Drift > -2*time.Second && result.Drift < 2*time.Second)
+}
+
+// At least one camera must be connected in order to run successfully.
+func TestGetPowerStatus(t *testing.T) {
+	e := NewEOSClient()
+	e.Initialize()
+	defer e.Release()
+
+	models, _ := e.GetCameraModels()
+	camera := models[0]
+	defer camera.Release()
+	assert.Nil(t, camera.OpenSession())
+	defer camera.CloseSession()
+
+	status, err := camera.GetPowerStatus()
+	assert.Nil(t, err)
+	if !status.ACPower {
+		assert.True(t, status.BatteryLevel > 0 && status.BatteryLevel <= 100)
+	}
+	assert.Nil(t, camera.SetBatteryThresholds(20, 10))
+	assert.Nil(t, camera.SetBatteryThresholds())
+}
@@ -0,0 +1,319 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+
+extern EdsError eosObjectEventHandler(EdsObjectEvent inEvent, EdsBaseRef inRef, EdsVoid *inContext);
+extern EdsError eosPropertyEventHandler(EdsPropertyEvent inEvent, EdsPropertyID inPropertyID, EdsUInt32 inParam, EdsVoid *inContext);
+extern EdsError eosStateEventHandler(EdsStateEvent inEvent, EdsUInt32 inEventData, EdsVoid *inContext);
+*/
+import (
+	"C"
+)
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+type EventType int
+
+const (
+	// A file was created on the camera's storage
+	ObjectCreated EventType = iota
+	// A file is waiting to be downloaded to the host
+	ObjectTransferRequested
+	// The value of a property changed
+	PropertyChanged
+	// The camera disconnected or is shutting down
+	CameraShutdown
+	// A movie file was created on the camera's storage, such as when
+	// recording stops
+	MovieCreated
+	// The battery level fell below a threshold set with SetBatteryThresholds
+	BatteryLow
+)
+
+// Something that happened on the camera.  Item is set for object events and
+// belongs to the subscriber, which must release it.
+type Event struct {
+	Type       EventType
+	Item       *DirectoryItem
+	PropertyID PropertyID
+
+	// Battery level and the threshold it fell below, for BatteryLow events
+	BatteryLevel     int
+	BatteryThreshold int
+}
+
+// Number of events a subscriber can fall behind by before events are dropped
+const eventBufferSize = 32
+
+// How long Capture waits for the camera to report the files of a shot
+var captureTimeout = 30 * time.Second
+
+// How long Capture keeps collecting files after the first one arrives, to
+// catch both halves of a RAW+JPEG shot
+var captureSettle = 500 * time.Millisecond
+
+// Subscribers for each camera with a session open, keyed by the context
+// handed to the SDK when registering event handlers
+var (
+	eventMutex       sync.Mutex
+	eventSubscribers = make(map[C.int][]chan Event)
+	nextEventContext C.int
+)
+
+// Register event handlers with the SDK for the camera.  Called when a
+// session is opened.
+func (c *CameraModel) registerEventHandlers() error {
+	eventMutex.Lock()
+	nextEventContext++
+	id := nextEventContext
+	eventSubscribers[id] = nil
+	eventMutex.Unlock()
+
+	handlerContext := C.malloc(C.size_t(unsafe.Sizeof(id)))
+	*(*C.int)(handlerContext) = id
+	c.eventContext = handlerContext
+
+	camera := (*C.struct___EdsObject)(unsafe.Pointer(c.camera))
+	if eosError := C.EdsSetObjectEventHandler(camera, C.kEdsObjectEvent_All, C.EdsObjectEventHandler(C.eosObjectEventHandler), handlerContext); eosError != C.EDS_ERR_OK {
+		c.unregisterEventHandlers()
+		return errors.New(fmt.Sprintf("Error when registering object event handler (code=%d)", eosError))
+	}
+	if eosError := C.EdsSetPropertyEventHandler(camera, C.kEdsPropertyEvent_All, C.EdsPropertyEventHandler(C.eosPropertyEventHandler), handlerContext); eosError != C.EDS_ERR_OK {
+		c.unregisterEventHandlers()
+		return errors.New(fmt.Sprintf("Error when registering property event handler (code=%d)", eosError))
+	}
+	if eosError := C.EdsSetCameraStateEventHandler(camera, C.kEdsStateEvent_All, C.EdsStateEventHandler(C.eosStateEventHandler), handlerContext); eosError != C.EDS_ERR_OK {
+		c.unregisterEventHandlers()
+		return errors.New(fmt.Sprintf("Error when registering state event handler (code=%d)", eosError))
+	}
+	return nil
+}
+
+// Remove the camera's event handlers and close its subscriptions.  Called
+// when the session is closed.
+func (c *CameraModel) unregisterEventHandlers() {
+	if c.eventContext == nil {
+		return
+	}
+	camera := (*C.struct___EdsObject)(unsafe.Pointer(c.camera))
+	C.EdsSetObjectEventHandler(camera, C.kEdsObjectEvent_All, nil, nil)
+	C.EdsSetPropertyEventHandler(camera, C.kEdsPropertyEvent_All, nil, nil)
+	C.EdsSetCameraStateEventHandler(camera, C.kEdsStateEvent_All, nil, nil)
+
+	id := *(*C.int)(c.eventContext)
+	eventMutex.Lock()
+	for _, subscriber := range eventSubscribers[id] {
+		close(subscriber)
+	}
+	delete(eventSubscribers, id)
+	delete(batteryMonitors, id)
+	eventMutex.Unlock()
+
+	C.free(c.eventContext)
+	c.eventContext = nil
+}
+
+// Receive the camera's events on the returned channel until Unsubscribe is
+// called or the session is closed.  Events are only delivered while
+// ProcessEvents is being called.
+func (c *CameraModel) Subscribe() (<-chan Event, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+	subscriber := make(chan Event, eventBufferSize)
+	id := *(*C.int)(c.eventContext)
+	eventMutex.Lock()
+	eventSubscribers[id] = append(eventSubscribers[id], subscriber)
+	eventMutex.Unlock()
+	return subscriber, nil
+}
+
+// Stop receiving events on a channel returned by Subscribe, releasing any
+// directory items still waiting in it
+func (c *CameraModel) Unsubscribe(events <-chan Event) {
+	if c.eventContext == nil {
+		return
+	}
+	id := *(*C.int)(c.eventContext)
+	eventMutex.Lock()
+	subscribers := eventSubscribers[id]
+	found := false
+	for i, subscriber := range subscribers {
+		if subscriber == events {
+			eventSubscribers[id] = append(subscribers[:i], subscribers[i+1:]...)
+			close(subscriber)
+			found = true
+			break
+		}
+	}
+	eventMutex.Unlock()
+	if !found {
+		return
+	}
+
+	for event := range events {
+		if event.Item != nil {
+			event.Item.Release()
+		}
+	}
+}
+
+// Deliver an event to every subscriber of the camera, retaining a separate
+// copy of the event's directory item for each one
+func publish(handlerContext unsafe.Pointer, event Event) {
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+
+	for _, subscriber := range eventSubscribers[*(*C.int)(handlerContext)] {
+		event := event
+		if event.Item != nil {
+			item := *event.Item
+			C.EdsRetain(item.ref)
+			event.Item = &item
+		}
+		select {
+		case subscriber <- event:
+		default:
+			if event.Item != nil {
+				event.Item.Release()
+			}
+		}
+	}
+}
+
+//export eosObjectEventHandler
+func eosObjectEventHandler(inEvent C.EdsObjectEvent, inRef C.EdsBaseRef, inContext unsafe.Pointer) C.EdsError {
+	if inEvent == C.kEdsObjectEvent_DirItemCreated || inEvent == C.kEdsObjectEvent_DirItemRequestTransfer {
+		C.EdsRetain(inRef)
+		if item, err := newDirectoryItem(inRef); err == nil {
+			event := Event{Type: ObjectTransferRequested, Item: item}
+			if inEvent == C.kEdsObjectEvent_DirItemCreated {
+				event.Type = ObjectCreated
+				if item.IsMovie() {
+					event.Type = MovieCreated
+				}
+			}
+			publish(inContext, event)
+			item.Release()
+		}
+	}
+	if inRef != nil {
+		C.EdsRelease(inRef)
+	}
+	return C.EDS_ERR_OK
+}
+
+//export eosPropertyEventHandler
+func eosPropertyEventHandler(inEvent C.EdsPropertyEvent, inPropertyID C.EdsPropertyID, inParam C.EdsUInt32, inContext unsafe.Pointer) C.EdsError {
+	if inEvent == C.kEdsPropertyEvent_PropertyChanged {
+		publish(inContext, Event{Type: PropertyChanged, PropertyID: PropertyID(inPropertyID)})
+		if inPropertyID == C.kEdsPropID_BatteryLevel {
+			checkBatteryLevel(inContext)
+		}
+	}
+	return C.EDS_ERR_OK
+}
+
+//export eosStateEventHandler
+func eosStateEventHandler(inEvent C.EdsStateEvent, inEventData C.EdsUInt32, inContext unsafe.Pointer) C.EdsError {
+	if inEvent == C.kEdsStateEvent_Shutdown {
+		publish(inContext, Event{Type: CameraShutdown})
+	}
+	return C.EDS_ERR_OK
+}
+
+// Dispatch pending camera events to subscribers.  Must be called regularly,
+// such as from a time.Ticker, while events are wanted.
+func (e *EOSClient) ProcessEvents() error {
+	if eosError := C.EdsGetEvent(); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when processing camera events (code=%d)", eosError))
+	}
+	return nil
+}
+
+// Take a picture and wait for the camera to report the files it produced,
+// processing events in the meantime.  The returned directory items must be
+// released by the caller.
+func (c *CameraModel) Capture(ctx context.Context, options ...TakePictureOption) ([]*DirectoryItem, error) {
+	events, err := c.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Unsubscribe(events)
+
+	if err := c.TakePicture(options...); err != nil {
+		return nil, err
+	}
+
+	var items []*DirectoryItem
+	deadline := time.Now().Add(captureTimeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		C.EdsGetEvent()
+		for len(events) > 0 {
+			event := <-events
+			switch {
+			case event.Type == CameraShutdown:
+				for _, item := range items {
+					item.Release()
+				}
+				return nil, errors.New("Camera shut down while waiting for captured files")
+			case event.Item != nil && event.Item.IsFolder:
+				event.Item.Release()
+			case event.Item != nil:
+				items = addCapturedItem(items, event)
+				deadline = time.Now().Add(captureSettle)
+			}
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			for _, item := range items {
+				item.Release()
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("Timed out waiting for the camera to report captured files")
+	}
+	return items, nil
+}
+
+// Add the item of an event to the files of a shot.  Saving to both the card
+// and the host reports each file twice, once as created and once as waiting
+// for transfer, so a file already collected is kept only once, as the
+// transfer request that it must be downloaded through.
+func addCapturedItem(items []*DirectoryItem, event Event) []*DirectoryItem {
+	for i, item := range items {
+		if item.Name != event.Item.Name {
+			continue
+		}
+		if event.Type == ObjectTransferRequested {
+			item.Release()
+			items[i] = event.Item
+		} else {
+			event.Item.Release()
+		}
+		return items
+	}
+	return append(items, event.Item)
+}
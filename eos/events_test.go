@@ -0,0 +1,23 @@
+package eos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddCapturedItem(t *testing.T) {
+	created := &DirectoryItem{Name: "IMG_0001.CR2"}
+	transfer := &DirectoryItem{Name: "IMG_0001.CR2"}
+	jpeg := &DirectoryItem{Name: "IMG_0001.JPG"}
+
+	// saving to both reports each file as created and as waiting for transfer
+	var items []*DirectoryItem
+	items = addCapturedItem(items, Event{Type: ObjectCreated, Item: created})
+	items = addCapturedItem(items, Event{Type: ObjectCreated, Item: jpeg})
+	items = addCapturedItem(items, Event{Type: ObjectTransferRequested, Item: transfer})
+	items = addCapturedItem(items, Event{Type: ObjectCreated, Item: &DirectoryItem{Name: "IMG_0001.JPG"}})
+	assert.Equal(t, 2, len(items))
+	assert.True(t, items[0] == transfer)
+	assert.True(t, items[1] == jpeg)
+}
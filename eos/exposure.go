@@ -0,0 +1,42 @@
+package eos
+
+// Tv, Av and ISO speed property values are counted in eighths of a stop,
+// with third stops at 3 and 5 and half stops at 4
+func codeStops(code uint32) float64 {
+	stops := float64(code / 8)
+	switch code % 8 {
+	case 3:
+		return stops + 1.0/3
+	case 5:
+		return stops + 2.0/3
+	default:
+		return stops + float64(code%8)/8
+	}
+}
+
+// Contribution of a Tv, Av or ISO speed property value to the exposure value
+// at ISO 100, in stops.  Returns false for values that aren't an exposure,
+// such as Bulb or auto ISO.
+func exposureStops(propertyID PropertyID, code uint32) (float64, bool) {
+	switch propertyID {
+	case PropertyTv:
+		// 0x38 is 1 second
+		if code < 0x10 || code > 0xA0 {
+			return 0, false
+		}
+		return codeStops(code) - 7, true
+	case PropertyAv:
+		// 0x08 is f/1.0
+		if code < 0x08 || code > 0x70 {
+			return 0, false
+		}
+		return codeStops(code) - 1, true
+	case PropertyISOSpeed:
+		// 0x48 is ISO 100
+		if code < 0x28 || code > 0xA0 {
+			return 0, false
+		}
+		return 9 - codeStops(code), true
+	}
+	return 0, false
+}
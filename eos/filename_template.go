@@ -0,0 +1,365 @@
+package eos
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urlgrey/canon-eos-go/geotag"
+	"github.com/urlgrey/canon-eos-go/xmp"
+)
+
+// Values substituted into a FilenameTemplate
+type FilenameFields struct {
+	// Capture time of the picture
+	Time time.Time
+
+	// Name of the file on the camera, such as IMG_0001.CR2
+	Original string
+
+	CameraSerial string
+	CameraOwner  string
+	Session      string
+
+	// Value of the persistent counter for this file
+	Sequence int
+}
+
+// Names downloaded files from fields of the picture, for example
+//
+//	{date:2006-01-02}/{camera.serial}_{seq:05}.{ext}
+//
+// Fields are written in braces, some taking a format after a colon:
+//
+//	{date:LAYOUT}   capture time formatted with a time.Format layout,
+//	                2006-01-02 if none is given
+//	{name}          original file name without its extension
+//	{ext}           original file extension without the dot
+//	{camera.serial} serial number of the camera body
+//	{camera.owner}  owner name set in the camera
+//	{session}       session name
+//	{seq:WIDTH}     counter, zero padded to WIDTH digits, from 1 to 10, if
+//	                given
+//
+// Slashes in the template, including date layouts, separate folders; slashes
+// in other field values are replaced so they can't add folders of their own.
+type FilenameTemplate struct {
+	parts []templatePart
+}
+
+// Widest {seq} field, more digits than any counter reaches
+const maxSequenceWidth = 10
+
+type templatePart struct {
+	literal string
+	field   string
+	format  string
+}
+
+// Parse a template, checking its fields are known
+func ParseFilenameTemplate(template string) (*FilenameTemplate, error) {
+	var parts []templatePart
+	for len(template) > 0 {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			parts = append(parts, templatePart{literal: template})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{literal: template[:open]})
+		}
+		end := strings.IndexByte(template[open:], '}')
+		if end < 0 {
+			return nil, errors.New(fmt.Sprintf("Unterminated field in filename template at %q", template[open:]))
+		}
+
+		field, format := template[open+1:open+end], ""
+		if colon := strings.IndexByte(field, ':'); colon >= 0 {
+			field, format = field[:colon], field[colon+1:]
+		}
+		switch field {
+		case "date":
+			if format == "" {
+				format = "2006-01-02"
+			}
+		case "seq":
+			if format != "" {
+				width, err := strconv.Atoi(format)
+				if err != nil || width < 1 || width > maxSequenceWidth {
+					return nil, errors.New(fmt.Sprintf("Invalid width %q for seq in filename template, must be 1 to %d", format, maxSequenceWidth))
+				}
+				format = strconv.Itoa(width)
+			}
+		case "name", "ext", "camera.serial", "camera.owner", "session":
+		default:
+			return nil, errors.New(fmt.Sprintf("Unknown field %q in filename template", field))
+		}
+		parts = append(parts, templatePart{field: field, format: format})
+		template = template[open+end+1:]
+	}
+	return &FilenameTemplate{parts: parts}, nil
+}
+
+// Build the relative path for a file, with forward slashes between folders
+func (t *FilenameTemplate) Execute(fields FilenameFields) (string, error) {
+	ext := path.Ext(fields.Original)
+	var name strings.Builder
+	for _, part := range t.parts {
+		var value string
+		switch part.field {
+		case "":
+			name.WriteString(part.literal)
+			continue
+		case "date":
+			// the layout comes from the template, so may add folders
+			name.WriteString(fields.Time.Format(part.format))
+			continue
+		case "name":
+			value = strings.TrimSuffix(fields.Original, ext)
+		case "ext":
+			value = strings.TrimPrefix(ext, ".")
+		case "camera.serial":
+			value = fields.CameraSerial
+		case "camera.owner":
+			value = fields.CameraOwner
+		case "session":
+			value = fields.Session
+		case "seq":
+			value = fmt.Sprintf("%0"+part.format+"d", fields.Sequence)
+		}
+		name.WriteString(strings.NewReplacer("/", "_", "\\", "_").Replace(value))
+	}
+
+	result := path.Clean(name.String())
+	if result == "." || path.IsAbs(result) || result == ".." || strings.HasPrefix(result, "../") {
+		return "", errors.New(fmt.Sprintf("Filename template gives invalid path %q", name.String()))
+	}
+	return result, nil
+}
+
+// Counter that survives restarts by keeping its value in a file, so sequence
+// numbers carry on from one session to the next
+type SequenceCounter struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// Create a counter stored in the named file.  The first value is one if the
+// file doesn't exist yet.
+func NewSequenceCounter(filename string) *SequenceCounter {
+	return &SequenceCounter{path: filename}
+}
+
+// Advance the counter and return its new value
+func (s *SequenceCounter) Next() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	value, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	return value + 1, s.write(value + 1)
+}
+
+// Value Next would return, without advancing the counter
+func (s *SequenceCounter) Peek() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	value, err := s.read()
+	return value + 1, err
+}
+
+// Record a value returned by Peek as used, so the counter carries on after
+// it.  Committing a value already passed leaves the counter as it is.
+func (s *SequenceCounter) Commit(value int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, err := s.read()
+	if err != nil || value <= current {
+		return err
+	}
+	return s.write(value)
+}
+
+// Last value used, zero if the file doesn't exist yet
+func (s *SequenceCounter) read() (int, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid sequence counter in %s", s.path))
+	}
+	return value, nil
+}
+
+func (s *SequenceCounter) write(value int) error {
+	if err := os.WriteFile(s.path+".part", []byte(strconv.Itoa(value)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".part", s.path)
+}
+
+// Downloads files into a directory, naming them with a template
+type Downloader struct {
+	Destination string
+	Template    *FilenameTemplate
+
+	// Supplies {seq}; if nil it is always zero
+	Counter *SequenceCounter
+
+	CameraSerial string
+	CameraOwner  string
+	Session      string
+
+	// Written as an XMP sidecar next to each RAW file downloaded, if set
+	Sidecar *xmp.Sidecar
+
+	// Places each file downloaded on a GPS track, if set.  JPEGs get the
+	// position in their Exif data and RAW files in their sidecar.  Files
+	// taken away from the track are left without one.
+	Geotagger *geotag.Geotagger
+}
+
+// Create a Downloader for files from the camera, filling in its serial
+// number and owner name
+func NewDownloader(camera *CameraModel, destination string, template string) (*Downloader, error) {
+	parsed, err := ParseFilenameTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := camera.GetSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := camera.GetOwnerName()
+	if err != nil {
+		return nil, err
+	}
+	return &Downloader{Destination: destination, Template: parsed, CameraSerial: serial, CameraOwner: owner}, nil
+}
+
+// Work out where Download would write the item.  The counter only advances
+// once a file has been downloaded, so this doesn't use up a number.
+func (d *Downloader) Path(item *DirectoryItem) (string, error) {
+	target, _, err := d.path(item)
+	return target, err
+}
+
+// Path for the item and the counter value it uses
+func (d *Downloader) path(item *DirectoryItem) (string, int, error) {
+	fields := FilenameFields{
+		Time:         item.Time,
+		Original:     item.Name,
+		CameraSerial: d.CameraSerial,
+		CameraOwner:  d.CameraOwner,
+		Session:      d.Session,
+	}
+	if d.Counter != nil {
+		var err error
+		if fields.Sequence, err = d.Counter.Peek(); err != nil {
+			return "", 0, err
+		}
+	}
+
+	name, err := d.Template.Execute(fields)
+	if err != nil {
+		return "", 0, err
+	}
+	return filepath.Join(d.Destination, filepath.FromSlash(name)), fields.Sequence, nil
+}
+
+// Download the item, returning the path it was written to.  An existing file
+// at that path is not overwritten.  The counter advances only once the file
+// has been written, so a failed download doesn't leave a gap in the sequence.
+func (d *Downloader) Download(item *DirectoryItem) (string, error) {
+	return d.download(item, item.Download)
+}
+
+// Download the item, with write copying its contents
+func (d *Downloader) download(item *DirectoryItem, write func(io.Writer) error) (string, error) {
+	target, sequence, err := d.path(item)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(target)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(target)
+		return "", err
+	}
+	if d.Counter != nil {
+		if err := d.Counter.Commit(sequence); err != nil {
+			return target, err
+		}
+	}
+	return target, d.writeSidecar(target)
+}
+
+// Write the sidecar for a downloaded file if it is a RAW file, and geotag it
+func (d *Downloader) writeSidecar(target string) error {
+	switch strings.ToUpper(filepath.Ext(target)) {
+	case ".CR2", ".CR3", ".CRW":
+	case ".JPG", ".JPEG":
+		if d.Geotagger == nil {
+			return nil
+		}
+		if err := d.Geotagger.TagJPEG(target); err != nil && err != geotag.ErrNoPosition {
+			return err
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if d.Geotagger == nil {
+		if d.Sidecar == nil {
+			return nil
+		}
+		return d.Sidecar.WriteFile(xmp.SidecarPath(target))
+	}
+
+	// the position differs for each file, so tag a copy of the sidecar
+	var sidecar xmp.Sidecar
+	if d.Sidecar != nil {
+		sidecar = *d.Sidecar
+	}
+	captured, err := geotag.CaptureTime(target)
+	if err != nil {
+		return err
+	}
+	if err := d.Geotagger.TagSidecar(&sidecar, captured); err != nil {
+		if err != geotag.ErrNoPosition {
+			return err
+		}
+		if d.Sidecar == nil {
+			return nil
+		}
+	}
+	return sidecar.WriteFile(xmp.SidecarPath(target))
+}
@@ -0,0 +1,137 @@
+package eos
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/xmp"
+)
+
+var testFields = FilenameFields{
+	Time:         time.Date(2015, 6, 1, 14, 30, 5, 0, time.UTC),
+	Original:     "IMG_0042.CR2",
+	CameraSerial: "012345678901",
+	CameraOwner:  "Studio A/B",
+	Session:      "sku-1234",
+	Sequence:     7,
+}
+
+func TestFilenameTemplate(t *testing.T) {
+	cases := map[string]string{
+		"{date:2006-01-02}/{camera.serial}_{seq:05}.{ext}": "2015-06-01/012345678901_00007.CR2",
+		"{date}/{name}.{ext}":                              "2015-06-01/IMG_0042.CR2",
+		"{date:2006/01/02}/{date:150405}_{seq}.{ext}":      "2015/06/01/143005_7.CR2",
+		"{session}/{camera.owner}-{name}.{ext}":            "sku-1234/Studio A_B-IMG_0042.CR2",
+		"plain.jpg":                                        "plain.jpg",
+	}
+	for template, expected := range cases {
+		parsed, err := ParseFilenameTemplate(template)
+		assert.Nil(t, err, template)
+		name, err := parsed.Execute(testFields)
+		assert.Nil(t, err, template)
+		assert.Equal(t, expected, name, template)
+	}
+}
+
+func TestFilenameTemplateErrors(t *testing.T) {
+	for _, template := range []string{"{date", "{lens}.jpg", "{seq:five}.jpg", "{seq:0}.jpg", "{seq:-5}.jpg", "{seq:11}.jpg"} {
+		_, err := ParseFilenameTemplate(template)
+		assert.NotNil(t, err, template)
+	}
+
+	for _, template := range []string{"../{name}.{ext}", "{session}/../../x", "/{name}"} {
+		parsed, err := ParseFilenameTemplate(template)
+		assert.Nil(t, err, template)
+		_, err = parsed.Execute(testFields)
+		assert.NotNil(t, err, template)
+	}
+}
+
+func TestSequenceCounter(t *testing.T) {
+	counterPath := filepath.Join(t.TempDir(), "counter")
+	counter := NewSequenceCounter(counterPath)
+	for expected := 1; expected <= 3; expected++ {
+		value, err := counter.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	// a new counter on the same file carries on
+	value, err := NewSequenceCounter(counterPath).Next()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, value)
+
+	// a value peeked at is only used up once committed
+	value, _ = counter.Peek()
+	assert.Equal(t, 5, value)
+	value, _ = counter.Peek()
+	assert.Equal(t, 5, value)
+	assert.Nil(t, counter.Commit(5))
+	assert.Nil(t, counter.Commit(3))
+	value, _ = counter.Next()
+	assert.Equal(t, 6, value)
+}
+
+func TestDownloaderPath(t *testing.T) {
+	template, _ := ParseFilenameTemplate("{date}/{camera.serial}_{seq:04}.{ext}")
+	destination := t.TempDir()
+	downloader := Downloader{
+		Destination:  destination,
+		Template:     template,
+		Counter:      NewSequenceCounter(filepath.Join(destination, "counter")),
+		CameraSerial: "012345678901",
+	}
+
+	// working out the path doesn't use up a number
+	item := &DirectoryItem{Name: "IMG_0042.JPG", Time: testFields.Time}
+	first, err := downloader.Path(item)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(destination, "2015-06-01", "012345678901_0001.JPG"), first)
+	again, _ := downloader.Path(item)
+	assert.Equal(t, first, again)
+}
+
+func TestDownloaderSequence(t *testing.T) {
+	template, _ := ParseFilenameTemplate("{seq:04}.{ext}")
+	destination := t.TempDir()
+	downloader := Downloader{Destination: destination, Template: template, Counter: NewSequenceCounter(filepath.Join(destination, "counter"))}
+	item := &DirectoryItem{Name: "IMG_0042.JPG", Time: testFields.Time}
+
+	// a failed download leaves no file and doesn't advance the counter
+	_, err := downloader.download(item, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("Error when downloading IMG_0042.JPG (code=129)")
+	})
+	assert.NotNil(t, err)
+	_, err = os.Stat(filepath.Join(destination, "0001.JPG"))
+	assert.True(t, os.IsNotExist(err))
+
+	for _, expected := range []string{"0001.JPG", "0002.JPG"} {
+		target, err := downloader.download(item, func(w io.Writer) error {
+			_, err := w.Write([]byte("jpeg"))
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(destination, expected), target)
+	}
+}
+
+func TestDownloaderSidecar(t *testing.T) {
+	destination := t.TempDir()
+	downloader := Downloader{Destination: destination, Sidecar: &xmp.Sidecar{Rating: 3, Keywords: []string{"SH-1042"}}}
+
+	assert.Nil(t, downloader.writeSidecar(filepath.Join(destination, "IMG_0001.CR2")))
+	data, err := os.ReadFile(filepath.Join(destination, "IMG_0001.xmp"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "SH-1042")
+
+	// JPEGs carry their metadata inside, so get no sidecar
+	assert.Nil(t, downloader.writeSidecar(filepath.Join(destination, "IMG_0002.JPG")))
+	_, err = os.Stat(filepath.Join(destination, "IMG_0002.xmp"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,104 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type FocusDirection int
+
+const (
+	TowardsFar FocusDirection = iota
+	TowardsNear
+)
+
+// Anything that can capture and drive its lens focus, normally a
+// *CameraModel with LiveView active
+type FocusStackCamera interface {
+	Capturer
+	DriveLensNear(step FocusStep) error
+	DriveLensFar(step FocusStep) error
+}
+
+// A sequence of pictures with the focus moved between each one, for focus
+// stacking.  Focus the lens on one end of the subject before starting; the
+// stack then works its way towards the other.  Pictures are always taken
+// without autofocus.
+type FocusStack struct {
+	Frames int
+
+	// Size of the lens drive steps between frames, and how many of them
+	// to make.  StepsPerFrame defaults to one.
+	Step          FocusStep
+	StepsPerFrame int
+
+	Direction FocusDirection
+
+	// Time to let the lens stop moving before each picture.  Defaults to
+	// half a second.
+	Settle time.Duration
+
+	// Options passed to every capture in addition to SkipAutoFocus
+	Options []TakePictureOption
+
+	// Defaults to the system clock
+	Clock Clock
+}
+
+// Take the stack, returning the files of every frame in the order they were
+// taken.  They must be released by the caller.
+func (f *FocusStack) Capture(ctx context.Context, camera FocusStackCamera) ([]*DirectoryItem, error) {
+	if f.Frames < 1 {
+		return nil, errors.New("FocusStack must have at least one frame")
+	}
+	if f.Step < FocusStepSmall || f.Step > FocusStepLarge {
+		return nil, errors.New("Unrecognized focus step supplied")
+	}
+	stepsPerFrame := f.StepsPerFrame
+	if stepsPerFrame <= 0 {
+		stepsPerFrame = 1
+	}
+	settle := f.Settle
+	if settle <= 0 {
+		settle = 500 * time.Millisecond
+	}
+	clock := f.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	drive := camera.DriveLensFar
+	if f.Direction == TowardsNear {
+		drive = camera.DriveLensNear
+	}
+	options := append([]TakePictureOption{SkipAutoFocus}, f.Options...)
+
+	var items []*DirectoryItem
+	release := func() {
+		for _, item := range items {
+			item.Release()
+		}
+	}
+	for frame := 0; frame < f.Frames; frame++ {
+		if frame > 0 {
+			for i := 0; i < stepsPerFrame; i++ {
+				if err := drive(f.Step); err != nil {
+					release()
+					return nil, err
+				}
+			}
+			if err := sleep(ctx, clock, settle); err != nil {
+				release()
+				return nil, err
+			}
+		}
+
+		frameItems, err := camera.Capture(ctx, options...)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		items = append(items, frameItems...)
+	}
+	return items, nil
+}
@@ -0,0 +1,77 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Camera that tracks the focus position of the lens as a count of small
+// steps away from where it started
+type fakeFocusCamera struct {
+	position  int
+	positions []int
+	options   [][]TakePictureOption
+	driveErr  error
+}
+
+var fakeStepSizes = map[FocusStep]int{FocusStepSmall: 1, FocusStepMedium: 10, FocusStepLarge: 100}
+
+func (c *fakeFocusCamera) DriveLensNear(step FocusStep) error {
+	c.position -= fakeStepSizes[step]
+	return c.driveErr
+}
+
+func (c *fakeFocusCamera) DriveLensFar(step FocusStep) error {
+	c.position += fakeStepSizes[step]
+	return c.driveErr
+}
+
+func (c *fakeFocusCamera) Capture(ctx context.Context, options ...TakePictureOption) ([]*DirectoryItem, error) {
+	c.positions = append(c.positions, c.position)
+	c.options = append(c.options, options)
+	return []*DirectoryItem{{Name: fmt.Sprintf("IMG_%04d.CR2", len(c.positions))}}, nil
+}
+
+func TestFocusStack(t *testing.T) {
+	camera := &fakeFocusCamera{}
+	stack := FocusStack{Frames: 4, Step: FocusStepMedium, StepsPerFrame: 2, Clock: &fakeClock{}}
+
+	items, err := stack.Capture(context.Background(), camera)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 20, 40, 60}, camera.positions)
+	assert.Equal(t, 4, len(items))
+	assert.Equal(t, "IMG_0004.CR2", items[3].Name)
+	for _, options := range camera.options {
+		assert.Equal(t, []TakePictureOption{SkipAutoFocus}, options)
+	}
+}
+
+func TestFocusStackTowardsNear(t *testing.T) {
+	camera := &fakeFocusCamera{}
+	stack := FocusStack{Frames: 3, Step: FocusStepSmall, Direction: TowardsNear, Clock: &fakeClock{}}
+
+	_, err := stack.Capture(context.Background(), camera)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, -1, -2}, camera.positions)
+}
+
+func TestFocusStackDriveFailure(t *testing.T) {
+	camera := &fakeFocusCamera{driveErr: errors.New("LiveView must be active to drive the lens")}
+	stack := FocusStack{Frames: 3, Step: FocusStepSmall, Clock: &fakeClock{}}
+
+	items, err := stack.Capture(context.Background(), camera)
+	assert.NotNil(t, err)
+	assert.Nil(t, items)
+	assert.Equal(t, 1, len(camera.positions))
+}
+
+func TestFocusStackRejectsInvalidSettings(t *testing.T) {
+	_, err := (&FocusStack{Step: FocusStepSmall}).Capture(context.Background(), &fakeFocusCamera{})
+	assert.NotNil(t, err)
+	_, err = (&FocusStack{Frames: 5}).Capture(context.Background(), &fakeFocusCamera{})
+	assert.NotNil(t, err)
+}
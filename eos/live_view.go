@@ -0,0 +1,150 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+*/
+import (
+	"C"
+)
+import (
+	"errors"
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// Magnification of the LiveView image
+type LiveViewZoom int
+
+const (
+	ZoomFit LiveViewZoom = C.kEdsEvfZoom_Fit
+	Zoom5x  LiveViewZoom = C.kEdsEvfZoom_x5
+	Zoom10x LiveViewZoom = C.kEdsEvfZoom_x10
+)
+
+// Returned by GetLiveViewFrame when the camera has no frame ready yet, which
+// is normal for a short time after LiveView starts
+var ErrLiveViewNotReady = errors.New("LiveView frame is not ready")
+
+// A single LiveView image downloaded to the PC
+type LiveViewFrame struct {
+	// JPEG encoded image
+	JPEG []byte
+
+	Zoom LiveViewZoom
+
+	// Top left corner of the magnified area, in the coordinates of the
+	// full sensor image
+	ZoomPosition image.Point
+
+	// Top left corner of the frame, in the coordinates of the full sensor
+	// image
+	ImagePosition image.Point
+}
+
+// Magnify the LiveView image.  The magnified area is the same as the AF area,
+// so it can be moved with SetLiveViewZoomPosition or
+// SetLiveViewAFAreaPosition.
+func (c *CameraModel) SetLiveViewZoom(zoom LiveViewZoom) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false {
+		return errors.New("LiveView must be active to zoom")
+	}
+
+	switch zoom {
+	case ZoomFit, Zoom5x, Zoom10x:
+	default:
+		return errors.New("Unrecognized LiveView zoom supplied")
+	}
+	return c.setUInt32Property(C.kEdsPropID_Evf_Zoom, uint32(zoom))
+}
+
+// Get the magnification of the LiveView image
+func (c *CameraModel) GetLiveViewZoom() (LiveViewZoom, error) {
+	if c.sessionOpen == false {
+		return 0, errors.New("Session is not open, must call OpenSession first")
+	}
+	zoom, err := c.getUInt32Property(C.kEdsPropID_Evf_Zoom)
+	return LiveViewZoom(zoom), err
+}
+
+// Move the magnified area so its top left corner is at the given position, in
+// the coordinates of the full sensor image
+func (c *CameraModel) SetLiveViewZoomPosition(position image.Point) error {
+	return c.SetLiveViewAFAreaPosition(position)
+}
+
+// Download the current LiveView image along with its zoom settings.
+// LiveView must be active with the PC output device.
+func (c *CameraModel) GetLiveViewFrame() (*LiveViewFrame, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false || c.liveViewDevice != C.kEdsEvfOutputDevice_PC {
+		return nil, errors.New("LiveView must be active on the PC output device to download frames")
+	}
+
+	var stream C.EdsStreamRef
+	if eosError := C.EdsCreateMemoryStream(0, &stream); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when creating stream for LiveView frame (code=%d)", eosError))
+	}
+	defer C.EdsRelease(stream)
+
+	var evfImage C.EdsEvfImageRef
+	if eosError := C.EdsCreateEvfImageRef(stream, &evfImage); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when creating LiveView image (code=%d)", eosError))
+	}
+	defer C.EdsRelease(evfImage)
+
+	eosError := C.EdsDownloadEvfImage((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), evfImage)
+	if eosError == C.EDS_ERR_OBJECT_NOTREADY {
+		return nil, ErrLiveViewNotReady
+	}
+	if eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when downloading LiveView frame (code=%d)", eosError))
+	}
+
+	jpeg, err := streamBytes(stream)
+	if err != nil {
+		return nil, err
+	}
+	zoom, err := getUInt32Property(evfImage, C.kEdsPropID_Evf_Zoom)
+	if err != nil {
+		return nil, err
+	}
+	zoomPosition, err := getPointProperty(evfImage, C.kEdsPropID_Evf_ZoomPosition)
+	if err != nil {
+		return nil, err
+	}
+	imagePosition, err := getPointProperty(evfImage, C.kEdsPropID_Evf_ImagePosition)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LiveViewFrame{
+		JPEG:          jpeg,
+		Zoom:          LiveViewZoom(zoom),
+		ZoomPosition:  zoomPosition,
+		ImagePosition: imagePosition,
+	}, nil
+}
+
+// Copy the contents of a memory stream
+func streamBytes(stream C.EdsStreamRef) ([]byte, error) {
+	var length C.EdsUInt64
+	if eosError := C.EdsGetLength(stream, &length); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when obtaining stream length (code=%d)", eosError))
+	}
+	var pointer unsafe.Pointer
+	if eosError := C.EdsGetPointer(stream, &pointer); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when obtaining stream data (code=%d)", eosError))
+	}
+	return C.GoBytes(pointer, C.int(length)), nil
+}
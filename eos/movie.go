@@ -0,0 +1,77 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+*/
+import (
+	"C"
+)
+import (
+	"errors"
+	"fmt"
+)
+
+// Values of the record property (kEdsPropID_Record)
+const (
+	recordEnd   uint32 = 0
+	recordBegin uint32 = 4
+)
+
+// Start recording a movie to the camera's card.  The camera must be in movie
+// mode with LiveView active.
+func (c *CameraModel) StartMovieRecording() error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	if c.liveViewActive == false {
+		return errors.New("LiveView must be active to record a movie")
+	}
+
+	aeMode, err := c.getUInt32Property(C.kEdsPropID_AEMode)
+	if err != nil {
+		return err
+	}
+	if aeMode != aeModeMovie {
+		return errors.New(fmt.Sprintf("Camera must be in movie mode to record a movie (mode=%d)", aeMode))
+	}
+
+	record, err := c.getUInt32Property(C.kEdsPropID_Record)
+	if err != nil {
+		return err
+	}
+	if record == recordBegin {
+		return errors.New("Movie is already recording, cannot start")
+	}
+	return c.setUInt32Property(C.kEdsPropID_Record, recordBegin)
+}
+
+// Stop recording a movie.  A MovieCreated event is sent to subscribers once
+// the camera has written the file.
+func (c *CameraModel) StopMovieRecording() error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+
+	record, err := c.getUInt32Property(C.kEdsPropID_Record)
+	if err != nil {
+		return err
+	}
+	if record != recordBegin {
+		return errors.New("Movie is not recording, cannot stop")
+	}
+	return c.setUInt32Property(C.kEdsPropID_Record, recordEnd)
+}
+
+// Whether a movie is currently recording
+func (c *CameraModel) IsRecordingMovie() (bool, error) {
+	if c.sessionOpen == false {
+		return false, errors.New("Session is not open, must call OpenSession first")
+	}
+	record, err := c.getUInt32Property(C.kEdsPropID_Record)
+	return record == recordBegin, err
+}
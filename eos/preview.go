@@ -0,0 +1,64 @@
+package eos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/urlgrey/canon-eos-go/cr2"
+	"github.com/urlgrey/canon-eos-go/cr3"
+)
+
+// How much of a RAW file DownloadPreview reads first to find where the
+// preview is.  Canon bodies write the directories that locate it at the
+// start of CR2 and CR3 files, well within this.
+var previewReadSize uint64 = 4 << 20
+
+// Read the full-size preview embedded in a RAW file of the given name and
+// size.  read extends the download to the first length bytes of the file and
+// returns them, so the file is only transferred up to the end of the preview.
+func readPreview(name string, size uint64, read func(length uint64) ([]byte, error)) ([]byte, error) {
+	var locate func(io.ReaderAt) (int64, int64, error)
+	switch strings.ToUpper(path.Ext(name)) {
+	case ".CR2":
+		locate = cr2.PreviewRange
+	case ".CR3":
+		locate = cr3.PreviewRange
+	default:
+		return nil, errors.New(fmt.Sprintf("%s is not a CR2 or CR3 file, cannot find preview", name))
+	}
+
+	headerSize := size
+	if headerSize > previewReadSize {
+		headerSize = previewReadSize
+	}
+	data, err := read(headerSize)
+	if err != nil {
+		return nil, err
+	}
+	offset, length, err := locate(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error when locating preview of %s: %s", name, err))
+	}
+	end := uint64(offset) + uint64(length)
+	if length < 2 || end > size {
+		return nil, errors.New(fmt.Sprintf("Preview of %s does not fit in the file", name))
+	}
+
+	if end > uint64(len(data)) {
+		if data, err = read(end); err != nil {
+			return nil, err
+		}
+	}
+	if uint64(len(data)) < end {
+		return nil, errors.New(fmt.Sprintf("Download of %s ended before its preview", name))
+	}
+	preview := data[offset:end]
+	if preview[0] != 0xFF || preview[1] != 0xD8 {
+		return nil, errors.New(fmt.Sprintf("Preview of %s is not a JPEG", name))
+	}
+	return append([]byte(nil), preview...), nil
+}
@@ -0,0 +1,97 @@
+package eos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// A CR2 file whose first IFD points to a preview after gap bytes of other
+// data, followed by the sensor data
+func buildPreviewCR2(t *testing.T, preview []byte, gap int) []byte {
+	b := tifftest.New(binary.LittleEndian)
+	b.Append([]byte{'C', 'R', 2, 0, 0, 0, 0, 0})
+	// header, then an IFD of two entries
+	start := uint32(16 + 2 + 2*12 + 4 + gap)
+	first := b.IFD([]tifftest.Entry{
+		b.Long(tiff.StripOffsets, start),
+		b.Long(tiff.StripByteCounts, uint32(len(preview))),
+	}, 0)
+	assert.Equal(t, uint32(16), first)
+	b.SetFirst(first)
+	b.Append(make([]byte, gap))
+	assert.Equal(t, start, b.Append(preview))
+	b.Append([]byte("sensor data"))
+	return b.Bytes()
+}
+
+// Reads a file as DownloadPreview does, recording how far it got
+type fakeDownload struct {
+	data       []byte
+	downloaded uint64
+	calls      int
+}
+
+func (f *fakeDownload) read(length uint64) ([]byte, error) {
+	f.calls++
+	if length > uint64(len(f.data)) {
+		length = uint64(len(f.data))
+	}
+	f.downloaded = length
+	return f.data[:length], nil
+}
+
+func TestReadPreview(t *testing.T) {
+	preview := []byte{0xFF, 0xD8, 0xFF, 0xD9, 'p', 'r', 'e', 'v', 'i', 'e', 'w'}
+	file := &fakeDownload{data: buildPreviewCR2(t, preview, 100)}
+
+	data, err := readPreview("IMG_0001.CR2", uint64(len(file.data)), file.read)
+	assert.Nil(t, err)
+	assert.Equal(t, preview, data)
+	assert.Equal(t, 1, file.calls)
+}
+
+func TestReadPreviewBeyondReadSize(t *testing.T) {
+	defer func(size uint64) { previewReadSize = size }(previewReadSize)
+	previewReadSize = 64
+
+	// the preview, holding a smaller JPEG of its own, starts past the
+	// first read and is downloaded exactly
+	thumbnail := []byte{0xFF, 0xD8, 0xFF, 0xD9}
+	preview := append(append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x06}, thumbnail...), 0xFF, 0xD9)
+	data := buildPreviewCR2(t, preview, 200)
+	file := &fakeDownload{data: data}
+
+	result, err := readPreview("IMG_0001.CR2", uint64(len(data)), file.read)
+	assert.Nil(t, err)
+	assert.Equal(t, preview, result)
+	assert.Equal(t, 2, file.calls)
+	assert.Equal(t, uint64(bytes.Index(data, []byte("sensor data"))), file.downloaded)
+}
+
+func TestReadPreviewErrors(t *testing.T) {
+	preview := []byte{0xFF, 0xD8, 0xFF, 0xD9}
+	data := buildPreviewCR2(t, preview, 0)
+
+	// a preview running past the end of the file is an error, not a
+	// shorter image
+	short := &fakeDownload{data: data[:len(data)-len("sensor data")-1]}
+	_, err := readPreview("IMG_0001.CR2", uint64(len(short.data)), short.read)
+	assert.NotNil(t, err)
+
+	// as is one that isn't a JPEG
+	data[bytes.Index(data, preview)] = 0
+	file := &fakeDownload{data: data}
+	_, err = readPreview("IMG_0001.CR2", uint64(len(data)), file.read)
+	assert.NotNil(t, err)
+
+	_, err = readPreview("MVI_0001.MOV", uint64(len(data)), file.read)
+	assert.NotNil(t, err)
+	file = &fakeDownload{data: []byte("not a raw file")}
+	_, err = readPreview("IMG_0001.CR3", uint64(len(file.data)), file.read)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,249 @@
+package eos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Properties saved in a profile, in the order they are restored: the
+// exposure mode, drive, metering and focus settings, exposure, flash, image
+// quality, colour and noise reduction settings, and the LiveView AF method.
+// The SDK has no way to list the properties a body supports, so this is every
+// single-value setting it documents as writable apart from those tied to the
+// host's session: where files are saved, LiveView's output device and
+// whether it is running, its white balance and depth of field preview, which
+// only last while the host shows LiveView, and the clock, which SyncClock
+// sets.  Custom functions, picture style parameters and white balance shift
+// aren't single values and aren't saved.
+//
+// The AE mode comes first as it decides which of the others can be set, and
+// white balance before the colour temperature it enables.
+var profileProperties = []struct {
+	id   PropertyID
+	name string
+}{
+	{PropertyAEModeSelect, "AEModeSelect"},
+	{PropertyAEMode, "AEMode"},
+	{PropertyDriveMode, "DriveMode"},
+	{PropertyMeteringMode, "MeteringMode"},
+	{PropertyAFMode, "AFMode"},
+	{PropertyTv, "Tv"},
+	{PropertyAv, "Av"},
+	{PropertyISOSpeed, "ISOSpeed"},
+	{PropertyExposureCompensation, "ExposureCompensation"},
+	{PropertyFlashOn, "FlashOn"},
+	{PropertyFlashCompensation, "FlashCompensation"},
+	{PropertyRedEye, "RedEye"},
+	{PropertyImageQuality, "ImageQuality"},
+	{PropertyWhiteBalance, "WhiteBalance"},
+	{PropertyColorTemperature, "ColorTemperature"},
+	{PropertyColorSpace, "ColorSpace"},
+	{PropertyPictureStyle, "PictureStyle"},
+	{PropertyNoiseReduction, "NoiseReduction"},
+	{PropertyEvfAFMode, "EvfAFMode"},
+}
+
+// Value a camera reports for a property it has no value for in its current
+// mode
+const propertyUnknown uint32 = 0xFFFFFFFF
+
+// Camera settings saved so they can be restored onto the same or another
+// camera, such as to set up several bodies identically for a shoot
+type Profile struct {
+	// Values keyed by property name, such as "Tv"
+	Properties map[string]uint32 `json:"properties"`
+}
+
+// Why a property was not saved to or restored from a profile
+type SkippedProperty struct {
+	Property string
+	Value    uint32
+	Reason   string
+}
+
+// Outcome of restoring a profile onto a camera
+type ProfileReport struct {
+	// Properties changed, and those that already had the profile's value
+	Set       []string
+	Unchanged []string
+
+	// Properties the camera doesn't support, can't change in its current
+	// mode, or doesn't allow the profile's value for
+	Skipped []SkippedProperty
+}
+
+// A property whose value differs between two profiles
+type ProfileDifference struct {
+	Property string
+
+	// Values in the first and second profiles; a profile without the
+	// property has HasBefore or HasAfter false
+	Before    uint32
+	HasBefore bool
+	After     uint32
+	HasAfter  bool
+}
+
+// Save the settings of a camera, listing the properties left out because the
+// camera doesn't support them or has no value for them in its current mode.
+// An error is only returned if none could be read.
+func SaveProfile(camera PropertyController) (*Profile, []SkippedProperty, error) {
+	profile := &Profile{Properties: make(map[string]uint32)}
+	var skipped []SkippedProperty
+	var firstErr error
+	for _, property := range profileProperties {
+		value, err := camera.GetProperty(property.id)
+		switch {
+		case err != nil:
+			if firstErr == nil {
+				firstErr = err
+			}
+			skipped = append(skipped, SkippedProperty{Property: property.name, Reason: fmt.Sprintf("can't be read: %s", err)})
+		case value == propertyUnknown:
+			skipped = append(skipped, SkippedProperty{Property: property.name, Value: value, Reason: "no value in the camera's current mode"})
+		default:
+			profile.Properties[property.name] = value
+		}
+	}
+	if len(profile.Properties) == 0 && firstErr != nil {
+		return nil, skipped, firstErr
+	}
+	return profile, skipped, nil
+}
+
+// Read a profile written by WriteFile
+func ReadProfile(name string) (*Profile, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error when reading profile %s: %s", name, err))
+	}
+	if profile.Properties == nil {
+		profile.Properties = make(map[string]uint32)
+	}
+	return &profile, nil
+}
+
+// Write the profile to the named file as JSON, replacing it in one step.
+// There is no YAML form, as the project vendors no YAML library, but YAML
+// tools read JSON as it is.
+func (p *Profile) WriteFile(name string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".part", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".part", name)
+}
+
+// Set the camera's properties to the profile's values.  Properties that
+// can't be set are skipped and listed in the report rather than stopping the
+// rest being restored.
+func (p *Profile) Restore(camera PropertyController) *ProfileReport {
+	report := &ProfileReport{}
+	known := make(map[string]bool)
+	for _, property := range profileProperties {
+		known[property.name] = true
+		value, ok := p.Properties[property.name]
+		if !ok {
+			continue
+		}
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, SkippedProperty{Property: property.name, Value: value, Reason: reason})
+		}
+
+		current, err := camera.GetProperty(property.id)
+		if err != nil {
+			skip("not supported by the camera")
+			continue
+		}
+		if current == value {
+			report.Unchanged = append(report.Unchanged, property.name)
+			continue
+		}
+
+		// properties that can't be changed in the current mode have no
+		// values to choose from, while some, such as colour temperature,
+		// take any value in a range and list none either
+		allowed, err := camera.GetPropertyValues(property.id)
+		if err != nil {
+			skip("not supported by the camera")
+			continue
+		}
+		if len(allowed) > 0 && !containsValue(allowed, value) {
+			skip(fmt.Sprintf("value 0x%x is not allowed by the camera in its current mode", value))
+			continue
+		}
+		if err := camera.SetProperty(property.id, value); err != nil {
+			skip(fmt.Sprintf("can't be changed: %s", err))
+			continue
+		}
+		report.Set = append(report.Set, property.name)
+	}
+
+	var unknown []string
+	for name := range p.Properties {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		report.Skipped = append(report.Skipped, SkippedProperty{Property: name, Value: p.Properties[name], Reason: "unknown property"})
+	}
+	return report
+}
+
+// List the properties whose values differ between two profiles, including
+// those only one of them has
+func DiffProfiles(before *Profile, after *Profile) []ProfileDifference {
+	names := make(map[string]bool)
+	for name := range before.Properties {
+		names[name] = true
+	}
+	for name := range after.Properties {
+		names[name] = true
+	}
+
+	// properties in restore order, then any this version doesn't know about
+	var ordered, unknown []string
+	for _, property := range profileProperties {
+		if names[property.name] {
+			ordered = append(ordered, property.name)
+			delete(names, property.name)
+		}
+	}
+	for name := range names {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	ordered = append(ordered, unknown...)
+
+	var differences []ProfileDifference
+	for _, name := range ordered {
+		beforeValue, hasBefore := before.Properties[name]
+		afterValue, hasAfter := after.Properties[name]
+		if hasBefore == hasAfter && beforeValue == afterValue {
+			continue
+		}
+		differences = append(differences, ProfileDifference{Property: name, Before: beforeValue, HasBefore: hasBefore, After: afterValue, HasAfter: hasAfter})
+	}
+	return differences
+}
+
+func containsValue(values []uint32, value uint32) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package eos
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Camera in Av mode, where the mode dial sets the AE mode and Tv is chosen
+// by the camera
+type fakeProfileCamera struct {
+	properties map[PropertyID]uint32
+	values     map[PropertyID][]uint32
+	readOnly   map[PropertyID]bool
+}
+
+func newFakeProfileCamera() *fakeProfileCamera {
+	return &fakeProfileCamera{
+		properties: map[PropertyID]uint32{
+			PropertyAEMode:           2,    // Av
+			PropertyTv:               0x70, // 1/125
+			PropertyAv:               0x38, // f/8
+			PropertyISOSpeed:         0x48, // ISO 100
+			PropertyWhiteBalance:     0,    // auto
+			PropertyColorTemperature: propertyUnknown,
+			PropertyPictureStyle:     0x81, // standard
+		},
+		values: map[PropertyID][]uint32{
+			PropertyAv:           {0x28, 0x30, 0x38},
+			PropertyISOSpeed:     {0x00, 0x48, 0x50, 0x58},
+			PropertyWhiteBalance: {0, 1, 9},
+			PropertyPictureStyle: {0x81, 0x82, 0x83},
+		},
+		readOnly: map[PropertyID]bool{PropertyAEMode: true, PropertyTv: true},
+	}
+}
+
+func (c *fakeProfileCamera) GetProperty(propertyID PropertyID) (uint32, error) {
+	value, ok := c.properties[propertyID]
+	if !ok {
+		return 0, errors.New("Error getting property (code=80)")
+	}
+	return value, nil
+}
+
+func (c *fakeProfileCamera) SetProperty(propertyID PropertyID, value uint32) error {
+	if c.readOnly[propertyID] {
+		return errors.New("Error setting property (code=41218)")
+	}
+	c.properties[propertyID] = value
+	return nil
+}
+
+func (c *fakeProfileCamera) GetPropertyValues(propertyID PropertyID) ([]uint32, error) {
+	if _, ok := c.properties[propertyID]; !ok {
+		return nil, errors.New("Error getting values of property (code=80)")
+	}
+	return c.values[propertyID], nil
+}
+
+func TestSaveProfile(t *testing.T) {
+	profile, skipped, err := SaveProfile(newFakeProfileCamera())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint32{"AEMode": 2, "Tv": 0x70, "Av": 0x38, "ISOSpeed": 0x48, "WhiteBalance": 0, "PictureStyle": 0x81}, profile.Properties)
+
+	// everything else is reported, unsupported properties and the colour
+	// temperature, which has no value outside its white balance mode
+	assert.Equal(t, len(profileProperties)-len(profile.Properties), len(skipped))
+	reasons := make(map[string]string)
+	for _, s := range skipped {
+		reasons[s.Property] = s.Reason
+	}
+	assert.Equal(t, "no value in the camera's current mode", reasons["ColorTemperature"])
+	assert.Contains(t, reasons["DriveMode"], "can't be read")
+
+	// LiveView state belongs to the host's session, not the camera's setup
+	live := newFakeProfileCamera()
+	live.properties[PropertyEvfMode] = 1
+	live.properties[PropertyEvfDepthOfField] = 1
+	profile, _, _ = SaveProfile(live)
+	_, saved := profile.Properties["EvfMode"]
+	assert.False(t, saved)
+	_, saved = profile.Properties["EvfDepthOfFieldPreview"]
+	assert.False(t, saved)
+
+	// a camera without a session has nothing to save
+	_, _, err = SaveProfile(&fakeProfileCamera{})
+	assert.NotNil(t, err)
+}
+
+func TestProfileFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "studio.json")
+	profile := &Profile{Properties: map[string]uint32{"Av": 0x30, "ISOSpeed": 0x50}}
+	assert.Nil(t, profile.WriteFile(name))
+
+	read, err := ReadProfile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, profile, read)
+
+	_, err = ReadProfile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestRestoreProfile(t *testing.T) {
+	camera := newFakeProfileCamera()
+	profile := &Profile{Properties: map[string]uint32{
+		"AEMode":       3,    // M, but the mode dial says otherwise
+		"Tv":           0x70, // already set
+		"Av":           0x30,
+		"ISOSpeed":     0x4B, // not offered by this camera
+		"WhiteBalance": 9,
+		"DriveMode":    1, // not supported
+		"Sharpness":    3,
+	}}
+
+	report := profile.Restore(camera)
+	assert.Equal(t, []string{"Av", "WhiteBalance"}, report.Set)
+	assert.Equal(t, []string{"Tv"}, report.Unchanged)
+	assert.Equal(t, 4, len(report.Skipped))
+	skipped := make(map[string]SkippedProperty)
+	for _, s := range report.Skipped {
+		skipped[s.Property] = s
+	}
+	assert.Contains(t, skipped["AEMode"].Reason, "can't be changed")
+	assert.Equal(t, uint32(3), skipped["AEMode"].Value)
+	assert.Contains(t, skipped["ISOSpeed"].Reason, "not allowed")
+	assert.Equal(t, "not supported by the camera", skipped["DriveMode"].Reason)
+	assert.Equal(t, "unknown property", skipped["Sharpness"].Reason)
+
+	assert.Equal(t, uint32(2), camera.properties[PropertyAEMode])
+	assert.Equal(t, uint32(0x30), camera.properties[PropertyAv])
+	assert.Equal(t, uint32(0x48), camera.properties[PropertyISOSpeed])
+	assert.Equal(t, uint32(9), camera.properties[PropertyWhiteBalance])
+}
+
+func TestDiffProfiles(t *testing.T) {
+	before := &Profile{Properties: map[string]uint32{"Tv": 0x70, "Av": 0x38, "ISOSpeed": 0x48, "Sharpness": 3}}
+	after := &Profile{Properties: map[string]uint32{"Tv": 0x70, "Av": 0x30, "PictureStyle": 0x82, "Sharpness": 3}}
+
+	assert.Equal(t, []ProfileDifference{
+		{Property: "Av", Before: 0x38, HasBefore: true, After: 0x30, HasAfter: true},
+		{Property: "ISOSpeed", Before: 0x48, HasBefore: true},
+		{Property: "PictureStyle", After: 0x82, HasAfter: true},
+	}, DiffProfiles(before, after))
+	assert.Empty(t, DiffProfiles(before, before))
+}
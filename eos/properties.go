@@ -0,0 +1,185 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+#include <string.h>
+*/
+import (
+	"C"
+)
+import (
+	"errors"
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// Camera properties that can be read with GetProperty and, where the camera
+// allows, written with SetProperty
+type PropertyID uint32
+
+const (
+	PropertyAEMode               PropertyID = C.kEdsPropID_AEMode
+	PropertyTv                   PropertyID = C.kEdsPropID_Tv
+	PropertyAv                   PropertyID = C.kEdsPropID_Av
+	PropertyISOSpeed             PropertyID = C.kEdsPropID_ISOSpeed
+	PropertyExposureCompensation PropertyID = C.kEdsPropID_ExposureCompensation
+	PropertyDriveMode            PropertyID = C.kEdsPropID_DriveMode
+	PropertyMeteringMode         PropertyID = C.kEdsPropID_MeteringMode
+	PropertyAFMode               PropertyID = C.kEdsPropID_AFMode
+	PropertyImageQuality         PropertyID = C.kEdsPropID_ImageQuality
+	PropertyWhiteBalance         PropertyID = C.kEdsPropID_WhiteBalance
+	PropertyColorTemperature     PropertyID = C.kEdsPropID_ColorTemperature
+	PropertyColorSpace           PropertyID = C.kEdsPropID_ColorSpace
+	PropertyPictureStyle         PropertyID = C.kEdsPropID_PictureStyle
+	PropertyBatteryLevel         PropertyID = C.kEdsPropID_BatteryLevel
+	PropertyBatteryQuality       PropertyID = C.kEdsPropID_BatteryQuality
+	PropertyAEModeSelect         PropertyID = C.kEdsPropID_AEModeSelect
+	PropertyFlashCompensation    PropertyID = C.kEdsPropID_FlashCompensation
+	PropertyNoiseReduction       PropertyID = C.kEdsPropID_NoiseReduction
+	PropertyFlashOn              PropertyID = C.kEdsPropID_FlashOn
+	PropertyRedEye               PropertyID = C.kEdsPropID_RedEye
+	PropertyEvfMode              PropertyID = C.kEdsPropID_Evf_Mode
+	PropertyEvfWhiteBalance      PropertyID = C.kEdsPropID_Evf_WhiteBalance
+	PropertyEvfColorTemperature  PropertyID = C.kEdsPropID_Evf_ColorTemperature
+	PropertyEvfDepthOfField      PropertyID = C.kEdsPropID_Evf_DepthOfFieldPreview
+	PropertyEvfAFMode            PropertyID = C.kEdsPropID_Evf_AFMode
+)
+
+// Values of the AE mode property (kEdsPropID_AEMode)
+const (
+	aeModeManual uint32 = C.kEdsAEMode_Manual
+	aeModeBulb   uint32 = C.kEdsAEMode_Bulb
+	aeModeMovie  uint32 = C.kEdsAEMode_Movie
+)
+
+// Tv property value that selects bulb on bodies without a B position on the
+// mode dial
+const tvBulb uint32 = 0x0C
+
+// Read the current value of a property
+func (c *CameraModel) GetProperty(propertyID PropertyID) (uint32, error) {
+	if c.sessionOpen == false {
+		return 0, errors.New("Session is not open, must call OpenSession first")
+	}
+	return c.getUInt32Property(C.EdsPropertyID(propertyID))
+}
+
+// Change the value of a property
+func (c *CameraModel) SetProperty(propertyID PropertyID, value uint32) error {
+	if c.sessionOpen == false {
+		return errors.New("Session is not open, must call OpenSession first")
+	}
+	return c.setUInt32Property(C.EdsPropertyID(propertyID), value)
+}
+
+// Get the values the camera currently allows for a property.  The list
+// depends on the mode, lens and other settings of the camera.
+func (c *CameraModel) GetPropertyValues(propertyID PropertyID) ([]uint32, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+
+	var desc C.EdsPropertyDesc
+	eosError := C.EdsGetPropertyDesc((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), C.EdsPropertyID(propertyID), &desc)
+	if eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error getting values of property 0x%x (code=%d)", propertyID, eosError))
+	}
+
+	values := make([]uint32, 0, int(desc.numElements))
+	for i := 0; i < int(desc.numElements); i++ {
+		values = append(values, uint32(desc.propDesc[i]))
+	}
+	return values, nil
+}
+
+// Get the serial number of the camera body
+func (c *CameraModel) GetSerialNumber() (string, error) {
+	if c.sessionOpen == false {
+		return "", errors.New("Session is not open, must call OpenSession first")
+	}
+	return c.getStringProperty(C.kEdsPropID_BodyIDEx)
+}
+
+// Get the owner name set in the camera
+func (c *CameraModel) GetOwnerName() (string, error) {
+	if c.sessionOpen == false {
+		return "", errors.New("Session is not open, must call OpenSession first")
+	}
+	return c.getStringProperty(C.kEdsPropID_OwnerName)
+}
+
+// Read a 32-bit unsigned property from the camera
+func (c *CameraModel) getUInt32Property(propertyID C.EdsPropertyID) (uint32, error) {
+	return getUInt32Property((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), propertyID)
+}
+
+// Read a 32-bit unsigned property from any SDK object
+func getUInt32Property(ref C.EdsBaseRef, propertyID C.EdsPropertyID) (uint32, error) {
+	var value C.EdsUInt32
+	eosError := C.EdsGetPropertyData(ref, propertyID, 0, (C.EdsUInt32)(unsafe.Sizeof(value)), unsafe.Pointer(&value))
+	if eosError != C.EDS_ERR_OK {
+		return 0, errors.New(fmt.Sprintf("Error getting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return uint32(value), nil
+}
+
+// Write a 32-bit unsigned property to the camera
+func (c *CameraModel) setUInt32Property(propertyID C.EdsPropertyID, value uint32) error {
+	data := C.EdsUInt32(value)
+	eosError := C.EdsSetPropertyData((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), propertyID, 0, (C.EdsUInt32)(unsafe.Sizeof(data)), unsafe.Pointer(&data))
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error setting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return nil
+}
+
+// Read a string property from the camera
+func (c *CameraModel) getStringProperty(propertyID C.EdsPropertyID) (string, error) {
+	camera := (*C.struct___EdsObject)(unsafe.Pointer(c.camera))
+	var dataType C.EdsDataType
+	var size C.EdsUInt32
+	if eosError := C.EdsGetPropertySize(camera, propertyID, 0, &dataType, &size); eosError != C.EDS_ERR_OK {
+		return "", errors.New(fmt.Sprintf("Error getting size of property 0x%x (code=%d)", propertyID, eosError))
+	}
+	if size == 0 {
+		return "", nil
+	}
+
+	buffer := (*C.char)(C.malloc(C.size_t(size)))
+	defer C.free(unsafe.Pointer(buffer))
+	if eosError := C.EdsGetPropertyData(camera, propertyID, 0, size, unsafe.Pointer(buffer)); eosError != C.EDS_ERR_OK {
+		return "", errors.New(fmt.Sprintf("Error getting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return C.GoStringN(buffer, C.int(C.strnlen(buffer, C.size_t(size)))), nil
+}
+
+// Read a point property from the camera
+func (c *CameraModel) getPointProperty(propertyID C.EdsPropertyID) (image.Point, error) {
+	return getPointProperty((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), propertyID)
+}
+
+// Read a point property from any SDK object
+func getPointProperty(ref C.EdsBaseRef, propertyID C.EdsPropertyID) (image.Point, error) {
+	var value C.EdsPoint
+	eosError := C.EdsGetPropertyData(ref, propertyID, 0, (C.EdsUInt32)(unsafe.Sizeof(value)), unsafe.Pointer(&value))
+	if eosError != C.EDS_ERR_OK {
+		return image.Point{}, errors.New(fmt.Sprintf("Error getting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return image.Pt(int(value.x), int(value.y)), nil
+}
+
+// Write a point property to the camera
+func (c *CameraModel) setPointProperty(propertyID C.EdsPropertyID, point image.Point) error {
+	data := C.EdsPoint{x: C.EdsInt32(point.X), y: C.EdsInt32(point.Y)}
+	eosError := C.EdsSetPropertyData((*C.struct___EdsObject)(unsafe.Pointer(c.camera)), propertyID, 0, (C.EdsUInt32)(unsafe.Sizeof(data)), unsafe.Pointer(&data))
+	if eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error setting property 0x%x (code=%d)", propertyID, eosError))
+	}
+	return nil
+}
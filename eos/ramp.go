@@ -0,0 +1,131 @@
+package eos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Anything whose properties can be read and changed, normally a *CameraModel
+type PropertyController interface {
+	GetProperty(propertyID PropertyID) (uint32, error)
+	SetProperty(propertyID PropertyID, value uint32) error
+	GetPropertyValues(propertyID PropertyID) ([]uint32, error)
+}
+
+// Inclusive range of property values
+type PropertyRange struct {
+	Min uint32
+	Max uint32
+}
+
+// Adjusts Tv, Av and ISO speed frame by frame to follow a curve of exposure
+// values, such as from day to night during a sunset time-lapse.  The camera
+// must be in M mode with none of the ramped properties on Bulb or auto.
+type ExposureRamp struct {
+	// Target exposure value at ISO 100 for each frame, such as 15 for full
+	// sun or -2 for a moonless landscape
+	Target func(frame int) float64
+
+	// Properties changed to follow the target, in the order they are used
+	// as the scene darkens; the order is reversed as it brightens.
+	// Defaults to PropertyTv then PropertyISOSpeed, leaving the aperture
+	// alone.
+	Properties []PropertyID
+
+	// Largest exposure change in stops requested between consecutive
+	// frames, so a sudden jump in the target is spread over several frames
+	// rather than showing as flicker.  The change is rounded to the nearest
+	// value the camera allows.  Defaults to a third of a stop.
+	MaxStep float64
+
+	// Restricts the values used for a property, such as keeping Tv shorter
+	// than the interval between frames
+	Limits map[PropertyID]PropertyRange
+}
+
+// Target exposure value moving linearly from start at the first frame to end
+// at the last of the given number of frames
+func LinearExposure(start, end float64, frames int) func(frame int) float64 {
+	return func(frame int) float64 {
+		if frames <= 1 || frame >= frames-1 {
+			return end
+		}
+		return start + (end-start)*float64(frame)/float64(frames-1)
+	}
+}
+
+// Move the camera's exposure one step towards the target for the frame,
+// returning the exposure value it ends up at
+func (r *ExposureRamp) adjust(camera PropertyController, frame int) (float64, error) {
+	if r.Target == nil {
+		return 0, errors.New("ExposureRamp has no target")
+	}
+
+	current := make(map[PropertyID]uint32)
+	var exposure float64
+	for _, propertyID := range []PropertyID{PropertyTv, PropertyAv, PropertyISOSpeed} {
+		value, err := camera.GetProperty(propertyID)
+		if err != nil {
+			return 0, err
+		}
+		stops, ok := exposureStops(propertyID, value)
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("Property 0x%x must have a fixed value for exposure ramping (value=0x%x)", propertyID, value))
+		}
+		current[propertyID] = value
+		exposure += stops
+	}
+
+	maxStep := r.MaxStep
+	if maxStep <= 0 {
+		maxStep = 1.0 / 3
+	}
+	remaining := math.Max(-maxStep, math.Min(maxStep, r.Target(frame)-exposure))
+
+	properties := r.Properties
+	if len(properties) == 0 {
+		properties = []PropertyID{PropertyTv, PropertyISOSpeed}
+	}
+	if remaining > 0 {
+		reversed := make([]PropertyID, len(properties))
+		for i, propertyID := range properties {
+			reversed[len(properties)-1-i] = propertyID
+		}
+		properties = reversed
+	}
+
+	for _, propertyID := range properties {
+		values, err := camera.GetPropertyValues(propertyID)
+		if err != nil {
+			return exposure, err
+		}
+		currentStops, _ := exposureStops(propertyID, current[propertyID])
+
+		// pick the allowed value that gets closest to the target, staying
+		// put unless a value is strictly closer
+		best, bestChange := current[propertyID], 0.0
+		for _, value := range values {
+			stops, ok := exposureStops(propertyID, value)
+			if !ok {
+				continue
+			}
+			if limit, ok := r.Limits[propertyID]; ok && (value < limit.Min || value > limit.Max) {
+				continue
+			}
+			change := stops - currentStops
+			if math.Abs(remaining-change) < math.Abs(remaining-bestChange)-1e-9 {
+				best, bestChange = value, change
+			}
+		}
+
+		if best != current[propertyID] {
+			if err := camera.SetProperty(propertyID, best); err != nil {
+				return exposure, err
+			}
+			exposure += bestChange
+			remaining -= bestChange
+		}
+	}
+	return exposure, nil
+}
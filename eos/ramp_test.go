@@ -0,0 +1,153 @@
+package eos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Camera in M mode with a fixed set of selectable Tv, Av and ISO values
+type fakeExposureCamera struct {
+	fakeCamera
+	properties map[PropertyID]uint32
+	values     map[PropertyID][]uint32
+	changes    int
+}
+
+func newFakeExposureCamera(clock *fakeClock) *fakeExposureCamera {
+	return &fakeExposureCamera{
+		fakeCamera: fakeCamera{clock: clock},
+		properties: map[PropertyID]uint32{
+			PropertyTv:       0x70, // 1/125
+			PropertyAv:       0x38, // f/8
+			PropertyISOSpeed: 0x48, // ISO 100
+		},
+		values: map[PropertyID][]uint32{
+			PropertyTv:       {0x0C, 0x10, 0x13, 0x15, 0x18, 0x1B, 0x1D, 0x20, 0x23, 0x25, 0x28, 0x2B, 0x2D, 0x30, 0x33, 0x35, 0x38, 0x3B, 0x3D, 0x40, 0x43, 0x45, 0x48, 0x4B, 0x4D, 0x50, 0x53, 0x55, 0x58, 0x5B, 0x5D, 0x60, 0x63, 0x65, 0x68, 0x6B, 0x6D, 0x70, 0x73, 0x75, 0x78},
+			PropertyAv:       {0x28, 0x2B, 0x2D, 0x30, 0x33, 0x35, 0x38},
+			PropertyISOSpeed: {0x00, 0x48, 0x4B, 0x4D, 0x50, 0x53, 0x55, 0x58, 0x5B, 0x5D, 0x60, 0x63, 0x65, 0x68},
+		},
+	}
+}
+
+func (c *fakeExposureCamera) GetProperty(propertyID PropertyID) (uint32, error) {
+	return c.properties[propertyID], nil
+}
+
+func (c *fakeExposureCamera) SetProperty(propertyID PropertyID, value uint32) error {
+	c.properties[propertyID] = value
+	c.changes++
+	return nil
+}
+
+func (c *fakeExposureCamera) GetPropertyValues(propertyID PropertyID) ([]uint32, error) {
+	return c.values[propertyID], nil
+}
+
+func TestExposureStops(t *testing.T) {
+	stops, ok := exposureStops(PropertyTv, 0x38)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, stops)
+	stops, _ = exposureStops(PropertyTv, 0x73)
+	assert.InDelta(t, 7+1.0/3, stops, 1e-9)
+	stops, _ = exposureStops(PropertyAv, 0x38)
+	assert.Equal(t, 6.0, stops)
+	stops, _ = exposureStops(PropertyISOSpeed, 0x58)
+	assert.Equal(t, -2.0, stops)
+
+	_, ok = exposureStops(PropertyTv, 0x0C)
+	assert.False(t, ok)
+	_, ok = exposureStops(PropertyISOSpeed, 0x00)
+	assert.False(t, ok)
+}
+
+func TestLinearExposure(t *testing.T) {
+	target := LinearExposure(12, 0, 4)
+	assert.Equal(t, 12.0, target(0))
+	assert.Equal(t, 8.0, target(1))
+	assert.Equal(t, 0.0, target(3))
+	assert.Equal(t, 0.0, target(10))
+}
+
+func TestExposureRampLimitsStepSize(t *testing.T) {
+	camera := newFakeExposureCamera(&fakeClock{})
+	ramp := ExposureRamp{Target: func(int) float64 { return 10 }}
+
+	// a three stop drop in the target is taken a third of a stop at a time
+	for i := 1; i <= 9; i++ {
+		exposure, err := ramp.adjust(camera, i)
+		assert.Nil(t, err)
+		assert.InDelta(t, 13-float64(i)/3, exposure, 1e-9)
+	}
+	assert.Equal(t, uint32(0x58), camera.properties[PropertyTv])
+	assert.Equal(t, 9, camera.changes)
+
+	// once on target nothing changes
+	_, err := ramp.adjust(camera, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, camera.changes)
+}
+
+func TestExposureRampUsesISOOnceTvIsLimited(t *testing.T) {
+	camera := newFakeExposureCamera(&fakeClock{})
+	ramp := ExposureRamp{
+		Target:  func(int) float64 { return 0 },
+		MaxStep: 100,
+		Limits:  map[PropertyID]PropertyRange{PropertyTv: {Min: 0x30, Max: 0xA0}},
+	}
+
+	exposure, err := ramp.adjust(camera, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0x30), camera.properties[PropertyTv])
+	assert.Equal(t, uint32(0x68), camera.properties[PropertyISOSpeed])
+	assert.Equal(t, uint32(0x38), camera.properties[PropertyAv])
+	assert.InDelta(t, 1, exposure, 1e-9)
+
+	// brightening again gives up ISO before shutter speed
+	ramp.Target = func(int) float64 { return 3 }
+	exposure, err = ramp.adjust(camera, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0x30), camera.properties[PropertyTv])
+	assert.Equal(t, uint32(0x58), camera.properties[PropertyISOSpeed])
+	assert.InDelta(t, 3, exposure, 1e-9)
+}
+
+func TestExposureRampRejectsAutoISO(t *testing.T) {
+	camera := newFakeExposureCamera(&fakeClock{})
+	camera.properties[PropertyISOSpeed] = 0
+	ramp := ExposureRamp{Target: func(int) float64 { return 10 }}
+
+	_, err := ramp.adjust(camera, 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, camera.changes)
+}
+
+func TestTimeLapseRampsExposure(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2015, 6, 1, 20, 0, 0, 0, time.UTC)}
+	camera := newFakeExposureCamera(clock)
+	events := make(chan TimeLapseEvent, 10)
+
+	timeLapse := TimeLapse{
+		Interval: 10 * time.Second,
+		Count:    4,
+		Ramp:     &ExposureRamp{Target: LinearExposure(13, 12, 4)},
+		Clock:    clock,
+		Events:   events,
+	}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	var exposures []float64
+	for _, event := range drain(events) {
+		assert.Equal(t, FrameTaken, event.Type)
+		exposures = append(exposures, event.Exposure)
+	}
+	assert.InDeltaSlice(t, []float64{13, 13 - 1.0/3, 13 - 2.0/3, 12}, exposures, 1e-9)
+}
+
+func TestTimeLapseRampNeedsPropertyController(t *testing.T) {
+	clock := &fakeClock{}
+	timeLapse := TimeLapse{Interval: time.Second, Ramp: &ExposureRamp{}, Clock: clock}
+	assert.NotNil(t, timeLapse.Run(context.Background(), &fakeCamera{clock: clock}))
+}
@@ -0,0 +1,458 @@
+package eos
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework EDSDK
+#define __MACOS__ 1
+#include <EDSDK/EDSDK.h>
+#include <EDSDK/EDSDKTypes.h>
+#include <stdlib.h>
+*/
+import (
+	"C"
+)
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/urlgrey/canon-eos-go/exif"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+type StorageType int
+
+const (
+	NoStorage StorageType = C.kEdsStorageType_Non
+	CF        StorageType = C.kEdsStorageType_CF
+	SD        StorageType = C.kEdsStorageType_SD
+	HD        StorageType = C.kEdsStorageType_HD
+	CFast     StorageType = C.kEdsStorageType_CFast
+)
+
+// File attribute flags of a DirectoryItem
+type FileAttributes uint32
+
+const (
+	ReadOnly FileAttributes = C.kEdsFileAttribute_ReadOnly
+	Hidden   FileAttributes = C.kEdsFileAttribute_Hidden
+	System   FileAttributes = C.kEdsFileAttribute_System
+	Archive  FileAttributes = C.kEdsFileAttribute_Archive
+)
+
+// A memory card or other storage in the camera.  Each Volume must be released
+// by invoking the Release function once no longer needed.
+type Volume struct {
+	ref C.EdsVolumeRef
+
+	Label       string
+	StorageType StorageType
+	Writable    bool
+	Capacity    uint64
+	FreeSpace   uint64
+
+	formatConfirmation string
+}
+
+// Returned by Format when the confirmation doesn't match
+var ErrFormatNotConfirmed = errors.New("Format confirmation does not match the volume, call FormatConfirmation first")
+
+// A file or folder on the camera's storage.  Each DirectoryItem holds a
+// reference to the camera object and must be released by invoking the
+// Release function once no longer needed.
+type DirectoryItem struct {
+	ref C.EdsDirectoryItemRef
+
+	Name     string
+	Size     uint64
+	IsFolder bool
+	Format   uint32
+	GroupID  uint32
+
+	// Write protection and other flags
+	Attributes FileAttributes
+
+	// Creation time according to the camera's clock, which has no time zone,
+	// so is taken to be in tiff.CameraLocation like the capture times read
+	// from the file's Exif data
+	Time time.Time
+
+	// Exif metadata of a JPEG file, filled in once it has been downloaded.
+	// Nil for other files and JPEGs without Exif data.
+	Exif *exif.Exif
+}
+
+// Get the storage volumes of the camera, one per card slot.  Empty slots are
+// reported with a StorageType of NoStorage.
+func (c *CameraModel) GetVolumes() ([]*Volume, error) {
+	if c.sessionOpen == false {
+		return nil, errors.New("Session is not open, must call OpenSession first")
+	}
+
+	camera := (*C.struct___EdsObject)(unsafe.Pointer(c.camera))
+	var count C.EdsUInt32
+	if eosError := C.EdsGetChildCount(camera, &count); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when obtaining count of volumes (code=%d)", eosError))
+	}
+
+	volumes := make([]*Volume, 0, int(count))
+	for i := 0; i < int(count); i++ {
+		var ref C.EdsVolumeRef
+		if eosError := C.EdsGetChildAtIndex(camera, C.EdsInt32(i), &ref); eosError != C.EDS_ERR_OK {
+			releaseVolumes(volumes)
+			return nil, errors.New(fmt.Sprintf("Error when obtaining reference to volume (code=%d)", eosError))
+		}
+
+		var info C.EdsVolumeInfo
+		if eosError := C.EdsGetVolumeInfo(ref, &info); eosError != C.EDS_ERR_OK {
+			C.EdsRelease(ref)
+			releaseVolumes(volumes)
+			return nil, errors.New(fmt.Sprintf("Error when obtaining volume info (code=%d)", eosError))
+		}
+
+		volumes = append(volumes, &Volume{
+			ref:         ref,
+			Label:       C.GoString(&info.szVolumeLabel[0]),
+			StorageType: StorageType(info.storageType),
+			Writable:    info.access == C.kEdsAccess_ReadWrite || info.access == C.kEdsAccess_Write,
+			Capacity:    uint64(info.maxCapacity),
+			FreeSpace:   uint64(info.freeSpaceInBytes),
+		})
+	}
+	return volumes, nil
+}
+
+func releaseVolumes(volumes []*Volume) {
+	for _, volume := range volumes {
+		volume.Release()
+	}
+}
+
+// Releases reference to the volume
+func (v *Volume) Release() {
+	if v.ref == nil {
+		return
+	}
+	C.EdsRelease(v.ref)
+	v.ref = nil
+}
+
+// Get the confirmation that must be passed to Format to erase this volume.
+// It is unique to this Volume, so a volume can't be formatted by mistake with
+// a value meant for another.
+func (v *Volume) FormatConfirmation() (string, error) {
+	if v.formatConfirmation == "" {
+		random := make([]byte, 4)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		v.formatConfirmation = fmt.Sprintf("format %s %s", v.Label, hex.EncodeToString(random))
+	}
+	return v.formatConfirmation, nil
+}
+
+// Erase everything on the volume.  The confirmation must be the value
+// returned by FormatConfirmation for this volume.
+func (v *Volume) Format(confirmation string) error {
+	if v.formatConfirmation == "" || confirmation != v.formatConfirmation {
+		return ErrFormatNotConfirmed
+	}
+	if !v.Writable {
+		return errors.New(fmt.Sprintf("Volume %s is not writable, cannot format", v.Label))
+	}
+	if eosError := C.EdsFormatVolume(v.ref); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when formatting volume %s (code=%d)", v.Label, eosError))
+	}
+	v.formatConfirmation = ""
+	v.FreeSpace = v.Capacity
+	return nil
+}
+
+// Get the files and folders at the top of the volume, sorted by name
+func (v *Volume) Children() ([]*DirectoryItem, error) {
+	return children(v.ref)
+}
+
+// Get the files and folders in a folder, sorted by name
+func (d *DirectoryItem) Children() ([]*DirectoryItem, error) {
+	if !d.IsFolder {
+		return nil, errors.New(fmt.Sprintf("%s is not a folder", d.Name))
+	}
+	return children(d.ref)
+}
+
+func children(ref C.EdsBaseRef) ([]*DirectoryItem, error) {
+	var count C.EdsUInt32
+	if eosError := C.EdsGetChildCount(ref, &count); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when obtaining count of directory items (code=%d)", eosError))
+	}
+
+	items := make([]*DirectoryItem, 0, int(count))
+	for i := 0; i < int(count); i++ {
+		var itemRef C.EdsDirectoryItemRef
+		if eosError := C.EdsGetChildAtIndex(ref, C.EdsInt32(i), &itemRef); eosError != C.EDS_ERR_OK {
+			releaseItems(items)
+			return nil, errors.New(fmt.Sprintf("Error when obtaining reference to directory item (code=%d)", eosError))
+		}
+		item, err := newDirectoryItem(itemRef)
+		if err != nil {
+			releaseItems(items)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func releaseItems(items []*DirectoryItem) {
+	for _, item := range items {
+		item.Release()
+	}
+}
+
+// Called by Walk for each file and folder.  The items in a folder are all
+// released once the walk has finished with that folder, so fn must not keep
+// them beyond it.  Returning filepath.SkipDir for a folder skips its
+// contents, and for a file the rest of its folder.
+type WalkFunc func(path string, item *DirectoryItem, err error) error
+
+// Walk the tree of files and folders on a volume, calling fn for each with
+// its path from the top of the volume, such as "DCIM/100CANON/IMG_0001.CR2".
+// Like filepath.WalkDir, items are visited in lexical order and fn is called
+// a second time with the error if a folder cannot be read.
+func Walk(volume *Volume, fn WalkFunc) error {
+	items, err := volume.Children()
+	if err != nil {
+		return err
+	}
+	return walkItems("", items, fn)
+}
+
+func walkItems(parent string, items []*DirectoryItem, fn WalkFunc) error {
+	defer releaseItems(items)
+
+	for _, item := range items {
+		itemPath := path.Join(parent, item.Name)
+		if err := walkItem(itemPath, item, fn); err != nil {
+			if err == filepath.SkipDir && !item.IsFolder {
+				// skip the rest of the parent folder
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func walkItem(itemPath string, item *DirectoryItem, fn WalkFunc) error {
+	if err := fn(itemPath, item, nil); err != nil || !item.IsFolder {
+		if err == filepath.SkipDir && item.IsFolder {
+			return nil
+		}
+		return err
+	}
+
+	items, err := item.Children()
+	if err != nil {
+		if err = fn(itemPath, item, err); err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+	return walkItems(itemPath, items, fn)
+}
+
+// Wrap a directory item reference, taking ownership of it
+func newDirectoryItem(ref C.EdsDirectoryItemRef) (*DirectoryItem, error) {
+	var info C.EdsDirectoryItemInfo
+	if eosError := C.EdsGetDirectoryItemInfo(ref, &info); eosError != C.EDS_ERR_OK {
+		C.EdsRelease(ref)
+		return nil, errors.New(fmt.Sprintf("Error when obtaining directory item info (code=%d)", eosError))
+	}
+
+	// attributes are only available for files
+	var attributes C.EdsFileAttributes
+	if info.isFolder == 0 {
+		if eosError := C.EdsGetAttribute(ref, &attributes); eosError != C.EDS_ERR_OK {
+			C.EdsRelease(ref)
+			return nil, errors.New(fmt.Sprintf("Error when obtaining attributes of %s (code=%d)", C.GoString(&info.szFileName[0]), eosError))
+		}
+	}
+
+	return &DirectoryItem{
+		ref:        ref,
+		Attributes: FileAttributes(attributes),
+		Name:       C.GoString(&info.szFileName[0]),
+		Size:       uint64(info.size),
+		IsFolder:   info.isFolder != 0,
+		Format:     uint32(info.format),
+		GroupID:    uint32(info.groupID),
+		Time:       cameraClockTime(int64(info.dateTime)),
+	}, nil
+}
+
+// Time of a file, which the SDK gives as the camera's clock reading counted
+// in seconds since 1970 as though the clock were set to UTC
+func cameraClockTime(seconds int64) time.Time {
+	clock := time.Unix(seconds, 0).UTC()
+	return time.Date(clock.Year(), clock.Month(), clock.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, tiff.CameraLocation)
+}
+
+// Releases reference to the directory item
+func (d *DirectoryItem) Release() {
+	if d.ref == nil {
+		return
+	}
+	C.EdsRelease(d.ref)
+	d.ref = nil
+}
+
+// Copy the contents of the file from the camera to w
+func (d *DirectoryItem) Download(w io.Writer) error {
+	if d.IsFolder {
+		return errors.New(fmt.Sprintf("%s is a folder, cannot download", d.Name))
+	}
+
+	var stream C.EdsStreamRef
+	if eosError := C.EdsCreateMemoryStream(C.EdsUInt64(d.Size), &stream); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when creating stream for download (code=%d)", eosError))
+	}
+	defer C.EdsRelease(stream)
+
+	if eosError := C.EdsDownload(d.ref, C.EdsUInt64(d.Size), stream); eosError != C.EDS_ERR_OK {
+		C.EdsDownloadCancel(d.ref)
+		return errors.New(fmt.Sprintf("Error when downloading %s (code=%d)", d.Name, eosError))
+	}
+	if eosError := C.EdsDownloadComplete(d.ref); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when completing download of %s (code=%d)", d.Name, eosError))
+	}
+
+	data, err := streamBytes(stream)
+	if err != nil {
+		return err
+	}
+	if d.isJPEG() {
+		d.Exif, _ = exif.Decode(bytes.NewReader(data))
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Download the thumbnail the camera keeps for the file, usually a small JPEG,
+// without transferring the file itself
+func (d *DirectoryItem) DownloadThumbnail() ([]byte, error) {
+	if d.IsFolder {
+		return nil, errors.New(fmt.Sprintf("%s is a folder, cannot download thumbnail", d.Name))
+	}
+
+	var stream C.EdsStreamRef
+	if eosError := C.EdsCreateMemoryStream(0, &stream); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when creating stream for thumbnail (code=%d)", eosError))
+	}
+	defer C.EdsRelease(stream)
+
+	if eosError := C.EdsDownloadThumbnail(d.ref, stream); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when downloading thumbnail of %s (code=%d)", d.Name, eosError))
+	}
+	return streamBytes(stream)
+}
+
+// Download the full-size JPEG preview embedded in a CR2 or CR3 file, found
+// from the file's directories.  Only the file up to the end of the preview is
+// transferred.  A JPEG file is its own preview and is downloaded in full.
+func (d *DirectoryItem) DownloadPreview() ([]byte, error) {
+	if d.IsFolder {
+		return nil, errors.New(fmt.Sprintf("%s is a folder, cannot download preview", d.Name))
+	}
+
+	if d.isJPEG() {
+		var buffer bytes.Buffer
+		if err := d.Download(&buffer); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	var stream C.EdsStreamRef
+	if eosError := C.EdsCreateMemoryStream(0, &stream); eosError != C.EDS_ERR_OK {
+		return nil, errors.New(fmt.Sprintf("Error when creating stream for preview (code=%d)", eosError))
+	}
+	defer C.EdsRelease(stream)
+
+	// each download carries on from where the last one stopped
+	var downloaded uint64
+	preview, err := readPreview(d.Name, d.Size, func(length uint64) ([]byte, error) {
+		if eosError := C.EdsDownload(d.ref, C.EdsUInt64(length-downloaded), stream); eosError != C.EDS_ERR_OK {
+			return nil, errors.New(fmt.Sprintf("Error when downloading preview of %s (code=%d)", d.Name, eosError))
+		}
+		downloaded = length
+		return streamBytes(stream)
+	})
+	if err != nil || downloaded < d.Size {
+		C.EdsDownloadCancel(d.ref)
+	} else {
+		C.EdsDownloadComplete(d.ref)
+	}
+	return preview, err
+}
+
+// Delete the file from the camera's storage and release the item.  Folders
+// can't be deleted, and write protected files must be unprotected first.
+func (d *DirectoryItem) Delete() error {
+	if d.IsFolder {
+		return errors.New(fmt.Sprintf("%s is a folder, cannot delete", d.Name))
+	}
+	if d.Attributes&ReadOnly != 0 {
+		return errors.New(fmt.Sprintf("%s is write protected, cannot delete", d.Name))
+	}
+	if eosError := C.EdsDeleteDirectoryItem(d.ref); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when deleting %s (code=%d)", d.Name, eosError))
+	}
+	d.Release()
+	return nil
+}
+
+// Set or clear write protection on the file
+func (d *DirectoryItem) SetProtected(protected bool) error {
+	if d.IsFolder {
+		return errors.New(fmt.Sprintf("%s is a folder, cannot protect", d.Name))
+	}
+
+	attributes := d.Attributes &^ ReadOnly
+	if protected {
+		attributes |= ReadOnly
+	}
+	if eosError := C.EdsSetAttribute(d.ref, C.EdsFileAttributes(attributes)); eosError != C.EDS_ERR_OK {
+		return errors.New(fmt.Sprintf("Error when changing protection of %s (code=%d)", d.Name, eosError))
+	}
+	d.Attributes = attributes
+	return nil
+}
+
+func (d *DirectoryItem) isJPEG() bool {
+	switch strings.ToUpper(path.Ext(d.Name)) {
+	case ".JPG", ".JPEG":
+		return true
+	}
+	return false
+}
+
+// Whether the item is a movie file
+func (d *DirectoryItem) IsMovie() bool {
+	switch strings.ToUpper(path.Ext(d.Name)) {
+	case ".MOV", ".MP4":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package eos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/exif"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+func TestDirectoryItemIsMovie(t *testing.T) {
+	assert.True(t, (&DirectoryItem{Name: "MVI_0001.MOV"}).IsMovie())
+	assert.True(t, (&DirectoryItem{Name: "mvi_0001.mp4"}).IsMovie())
+	assert.False(t, (&DirectoryItem{Name: "IMG_0001.CR2"}).IsMovie())
+}
+
+func TestCameraClockTime(t *testing.T) {
+	defer func(location *time.Location) { tiff.CameraLocation = location }(tiff.CameraLocation)
+	tiff.CameraLocation = time.FixedZone("+10:00", 10*3600)
+
+	// a JPEG taken at 12:30:45 by the camera's clock, without an offset
+	b := tifftest.New(binary.LittleEndian)
+	exifIFD := b.IFD([]tifftest.Entry{b.ASCII(tiff.DateTimeOriginal, "2015:06:01 12:30:45")}, 0)
+	b.SetFirst(b.IFD([]tifftest.Entry{b.Long(tiff.ExifIFDPointer, exifIFD)}, 0))
+	app1 := append([]byte("Exif\x00\x00"), b.Bytes()...)
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}, app1...)
+	metadata, err := exif.Decode(bytes.NewReader(append(jpeg, 0xFF, 0xD9)))
+	assert.Nil(t, err)
+
+	// the card reports the same reading, and both agree on the instant
+	item := cameraClockTime(time.Date(2015, 6, 1, 12, 30, 45, 0, time.UTC).Unix())
+	assert.True(t, item.Equal(time.Date(2015, 6, 1, 2, 30, 45, 0, time.UTC)))
+	assert.True(t, item.Equal(metadata.Time))
+	assert.Equal(t, "12:30:45", item.Format("15:04:05"))
+}
+
+func TestFormatConfirmation(t *testing.T) {
+	volume := &Volume{Label: "EOS_DIGITAL"}
+	other := &Volume{Label: "EOS_DIGITAL"}
+	assert.Equal(t, ErrFormatNotConfirmed, volume.Format(""))
+
+	confirmation, err := volume.FormatConfirmation()
+	assert.Nil(t, err)
+	assert.Contains(t, confirmation, "EOS_DIGITAL")
+	again, _ := volume.FormatConfirmation()
+	assert.Equal(t, confirmation, again)
+
+	otherConfirmation, _ := other.FormatConfirmation()
+	assert.NotEqual(t, confirmation, otherConfirmation)
+	assert.Equal(t, ErrFormatNotConfirmed, volume.Format(otherConfirmation))
+}
+
+func TestDirectoryItemProtection(t *testing.T) {
+	folder := &DirectoryItem{Name: "100CANON", IsFolder: true}
+	assert.NotNil(t, folder.SetProtected(true))
+	assert.NotNil(t, folder.Delete())
+
+	protected := &DirectoryItem{Name: "IMG_0001.CR2", Attributes: ReadOnly}
+	assert.NotNil(t, protected.Delete())
+}
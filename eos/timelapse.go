@@ -0,0 +1,180 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Anything that can take a picture, normally a *CameraModel
+type PictureTaker interface {
+	TakePicture(options ...TakePictureOption) error
+}
+
+// Source of time used to schedule a TimeLapse.  Replace it to drive a
+// TimeLapse from something other than the system clock, such as in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type TimeLapseEventType int
+
+const (
+	FrameTaken TimeLapseEventType = iota
+	FrameSkipped
+	FrameFailed
+)
+
+// Progress of a running TimeLapse, one per scheduled frame
+type TimeLapseEvent struct {
+	Type      TimeLapseEventType
+	Frame     int
+	Scheduled time.Time
+	Taken     time.Time
+	Err       error
+
+	// Exposure value the frame was taken at, when ramping exposure
+	Exposure float64
+}
+
+// Takes a picture every Interval.  Frame n is scheduled at n*Interval after
+// the sequence starts, measured on the monotonic clock, so slow captures do
+// not push later frames back.
+type TimeLapse struct {
+	Interval time.Duration
+
+	// Number of frames to schedule, including any that are skipped.  Zero
+	// means no limit.
+	Count int
+
+	// Wall clock times bounding the sequence.  A zero Start begins
+	// immediately, a zero Stop never ends the sequence.
+	Start time.Time
+	Stop  time.Time
+
+	// Skip frames that fall due while the camera is still busy with an
+	// earlier one, rather than taking them late.  Frames the camera
+	// rejects with ErrDeviceBusy are skipped instead of failing.
+	SkipIfBusy bool
+
+	// Options passed to every TakePicture call
+	Options []TakePictureOption
+
+	// Adjusts the exposure before each frame.  The camera passed to Run
+	// must then also be a PropertyController.
+	Ramp *ExposureRamp
+
+	// Receives an event for every scheduled frame.  Sends never block, so
+	// the channel should be buffered; events are dropped when it is full.
+	Events chan<- TimeLapseEvent
+
+	// Defaults to the system clock
+	Clock Clock
+}
+
+// Run the time-lapse until Count frames have been scheduled, Stop is reached
+// or the context is cancelled.  Failed frames are reported through Events and
+// do not end the sequence.
+func (t *TimeLapse) Run(ctx context.Context, camera PictureTaker) error {
+	if t.Interval <= 0 {
+		return errors.New("TimeLapse interval must be positive")
+	}
+	if t.Count < 0 {
+		return errors.New("TimeLapse count must not be negative")
+	}
+	var controller PropertyController
+	if t.Ramp != nil {
+		var ok bool
+		if controller, ok = camera.(PropertyController); !ok {
+			return errors.New("TimeLapse exposure ramping needs a camera whose properties can be set")
+		}
+	}
+	clock := t.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	// wait for the start time, then take the base for the schedule from
+	// the clock so it carries a monotonic reading
+	if wait := t.Start.Sub(clock.Now()); !t.Start.IsZero() && wait > 0 {
+		if err := sleep(ctx, clock, wait); err != nil {
+			return err
+		}
+	}
+	start := clock.Now()
+
+	// when the last picture finished, so frames that fell due while the
+	// camera was still taking it can be skipped
+	var busyUntil time.Time
+
+	for frame := 0; t.Count == 0 || frame < t.Count; frame++ {
+		scheduled := start.Add(time.Duration(frame) * t.Interval)
+		if !t.Stop.IsZero() && scheduled.After(t.Stop) {
+			break
+		}
+
+		now := clock.Now()
+		if t.SkipIfBusy && busyUntil.After(scheduled) {
+			t.emit(TimeLapseEvent{Type: FrameSkipped, Frame: frame, Scheduled: scheduled, Err: ErrDeviceBusy})
+			continue
+		}
+		if err := sleep(ctx, clock, scheduled.Sub(now)); err != nil {
+			return err
+		}
+
+		var exposure float64
+		if t.Ramp != nil {
+			var err error
+			if exposure, err = t.Ramp.adjust(controller, frame); err != nil {
+				t.emit(TimeLapseEvent{Type: FrameFailed, Frame: frame, Scheduled: scheduled, Err: err})
+				continue
+			}
+		}
+
+		taken := clock.Now()
+		err := camera.TakePicture(t.Options...)
+		busyUntil = clock.Now()
+		switch {
+		case err == nil:
+			t.emit(TimeLapseEvent{Type: FrameTaken, Frame: frame, Scheduled: scheduled, Taken: taken, Exposure: exposure})
+		case err == ErrDeviceBusy && t.SkipIfBusy:
+			t.emit(TimeLapseEvent{Type: FrameSkipped, Frame: frame, Scheduled: scheduled, Err: err})
+		default:
+			t.emit(TimeLapseEvent{Type: FrameFailed, Frame: frame, Scheduled: scheduled, Taken: taken, Err: err})
+		}
+	}
+	return nil
+}
+
+func (t *TimeLapse) emit(event TimeLapseEvent) {
+	if t.Events == nil {
+		return
+	}
+	select {
+	case t.Events <- event:
+	default:
+	}
+}
+
+// Wait on the clock for the given duration, returning early with the
+// context's error if it is cancelled
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,178 @@
+package eos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Clock that jumps forward instantly whenever it is waited on
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// Clock that also moves on a little every time it is read, like a real one
+type tickingClock struct {
+	fakeClock
+	tick time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.now = c.now.Add(c.tick)
+	return c.now
+}
+
+// Camera that records when pictures were taken, spending captureTime on each
+type fakeCamera struct {
+	clock       *fakeClock
+	captureTime time.Duration
+	errors      []error
+	taken       []time.Time
+}
+
+func (c *fakeCamera) TakePicture(options ...TakePictureOption) error {
+	c.taken = append(c.taken, c.clock.now)
+	c.clock.now = c.clock.now.Add(c.captureTime)
+	if len(c.errors) > 0 {
+		err := c.errors[0]
+		c.errors = c.errors[1:]
+		return err
+	}
+	return nil
+}
+
+func drain(events chan TimeLapseEvent) []TimeLapseEvent {
+	close(events)
+	var result []TimeLapseEvent
+	for event := range events {
+		result = append(result, event)
+	}
+	return result
+}
+
+func TestTimeLapseDoesNotDrift(t *testing.T) {
+	start := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	camera := &fakeCamera{clock: clock, captureTime: 700 * time.Millisecond}
+	events := make(chan TimeLapseEvent, 10)
+
+	timeLapse := TimeLapse{Interval: time.Second, Count: 5, Clock: clock, Events: events}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	assert.Equal(t, 5, len(camera.taken))
+	for i, taken := range camera.taken {
+		assert.Equal(t, start.Add(time.Duration(i)*time.Second), taken)
+	}
+	for i, event := range drain(events) {
+		assert.Equal(t, FrameTaken, event.Type)
+		assert.Equal(t, i, event.Frame)
+		assert.Equal(t, event.Scheduled, event.Taken)
+	}
+}
+
+func TestTimeLapseWaitsForStartAndEndsAtStop(t *testing.T) {
+	now := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: now}
+	camera := &fakeCamera{clock: clock}
+
+	timeLapse := TimeLapse{
+		Interval: 10 * time.Second,
+		Start:    now.Add(time.Minute),
+		Stop:     now.Add(2 * time.Minute),
+		Clock:    clock,
+	}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	assert.Equal(t, 7, len(camera.taken))
+	assert.Equal(t, now.Add(time.Minute), camera.taken[0])
+	assert.Equal(t, now.Add(2*time.Minute), camera.taken[6])
+}
+
+func TestTimeLapseSkipsFramesWhileBusy(t *testing.T) {
+	start := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	camera := &fakeCamera{clock: clock, captureTime: 1500 * time.Millisecond}
+	events := make(chan TimeLapseEvent, 10)
+
+	timeLapse := TimeLapse{Interval: time.Second, Count: 6, SkipIfBusy: true, Clock: clock, Events: events}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	assert.Equal(t, []time.Time{start, start.Add(2 * time.Second), start.Add(4 * time.Second)}, camera.taken)
+	var types []TimeLapseEventType
+	for _, event := range drain(events) {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []TimeLapseEventType{FrameTaken, FrameSkipped, FrameTaken, FrameSkipped, FrameTaken, FrameSkipped}, types)
+}
+
+func TestTimeLapseTakesFirstFrameWithSkip(t *testing.T) {
+	clock := &tickingClock{fakeClock: fakeClock{now: time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)}, tick: time.Millisecond}
+	camera := &fakeCamera{clock: &clock.fakeClock, captureTime: 200 * time.Millisecond}
+	events := make(chan TimeLapseEvent, 10)
+
+	timeLapse := TimeLapse{Interval: time.Second, Count: 3, SkipIfBusy: true, Clock: clock, Events: events}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	assert.Equal(t, 3, len(camera.taken))
+	for _, event := range drain(events) {
+		assert.Equal(t, FrameTaken, event.Type, "frame %d", event.Frame)
+	}
+}
+
+func TestTimeLapseTakesLateFramesWithoutSkip(t *testing.T) {
+	start := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	camera := &fakeCamera{clock: clock, captureTime: 1500 * time.Millisecond}
+
+	timeLapse := TimeLapse{Interval: time.Second, Count: 3, Clock: clock}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	assert.Equal(t, []time.Time{start, start.Add(1500 * time.Millisecond), start.Add(3 * time.Second)}, camera.taken)
+}
+
+func TestTimeLapseReportsFailures(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)}
+	failure := errors.New("Error when taking picture (code=2)")
+	camera := &fakeCamera{clock: clock, errors: []error{ErrDeviceBusy, failure}}
+	events := make(chan TimeLapseEvent, 10)
+
+	timeLapse := TimeLapse{Interval: time.Second, Count: 3, SkipIfBusy: true, Clock: clock, Events: events}
+	assert.Nil(t, timeLapse.Run(context.Background(), camera))
+
+	result := drain(events)
+	assert.Equal(t, 3, len(result))
+	assert.Equal(t, FrameSkipped, result[0].Type)
+	assert.Equal(t, FrameFailed, result[1].Type)
+	assert.Equal(t, failure, result[1].Err)
+	assert.Equal(t, FrameTaken, result[2].Type)
+}
+
+func TestTimeLapseCancel(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)}
+	camera := &fakeCamera{clock: clock}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	timeLapse := TimeLapse{Interval: time.Second, Clock: clock}
+	assert.Equal(t, context.Canceled, timeLapse.Run(ctx, camera))
+	assert.Equal(t, 0, len(camera.taken))
+}
+
+func TestTimeLapseRejectsInvalidInterval(t *testing.T) {
+	timeLapse := TimeLapse{}
+	assert.NotNil(t, timeLapse.Run(context.Background(), nil))
+}
@@ -0,0 +1,262 @@
+// Package exif reads the Exif metadata of JPEG captures and RAW files:
+// exposure, lens, capture time, orientation and GPS position.
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/urlgrey/canon-eos-go/cr2"
+	"github.com/urlgrey/canon-eos-go/cr3"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// Tags of the GPS IFD
+const (
+	GPSVersionID    uint16 = 0x0000
+	GPSLatitudeRef  uint16 = 0x0001
+	GPSLatitude     uint16 = 0x0002
+	GPSLongitudeRef uint16 = 0x0003
+	GPSLongitude    uint16 = 0x0004
+	GPSAltitudeRef  uint16 = 0x0005
+	GPSAltitude     uint16 = 0x0006
+	GPSTimeStamp    uint16 = 0x0007
+	GPSDateStamp    uint16 = 0x001D
+)
+
+// Position recorded with the picture
+type GPS struct {
+	// Degrees, negative south of the equator and west of Greenwich
+	Latitude  float64
+	Longitude float64
+
+	// Metres above sea level, negative below it
+	Altitude    float64
+	HasAltitude bool
+
+	// Time of the fix in UTC, zero if not recorded
+	Time time.Time
+}
+
+// Exif metadata of a picture.  Fields the file doesn't record are left at
+// their zero values.
+type Exif struct {
+	Make         string
+	Model        string
+	SerialNumber string
+
+	LensModel        string
+	LensSerialNumber string
+
+	// Exposure time in seconds, f-number, ISO speed and exposure
+	// compensation in stops
+	ExposureTime         float64
+	FNumber              float64
+	ISO                  int
+	ExposureCompensation float64
+	ExposureProgram      int
+	MeteringMode         int
+	Flash                int
+
+	// Focal length in mm, and its equivalent on a 35mm frame
+	FocalLength     float64
+	FocalLength35mm int
+
+	// Capture time including fractions of a second, in tiff.CameraLocation
+	// unless the file records its offset from UTC
+	Time time.Time
+
+	// 1 when the picture is upright, 6 when the camera was turned clockwise
+	// and 8 when turned anticlockwise
+	Orientation int
+
+	Width  int
+	Height int
+
+	// Nil if no position was recorded
+	GPS *GPS
+}
+
+// Read the Exif metadata of a JPEG file
+func Decode(r io.ReaderAt) (*Exif, error) {
+	reader, err := tiff.NewJPEGReader(r)
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := reader.ReadIFD(reader.First)
+	if err != nil {
+		return nil, err
+	}
+	exif, err := reader.ReadSubIFD(ifd0, tiff.ExifIFDPointer)
+	if err != nil {
+		return nil, err
+	}
+	gps, err := reader.ReadSubIFD(ifd0, tiff.GPSIFDPointer)
+	if err != nil {
+		return nil, err
+	}
+	return FromIFDs(ifd0, exif, gps), nil
+}
+
+// Read the Exif metadata of a JPEG, CR2 or CR3 file
+func Read(r io.ReaderAt) (*Exif, error) {
+	magic := make([]byte, 12)
+	n, _ := r.ReadAt(magic, 0)
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0xFF, 0xD8}):
+		return Decode(r)
+	case len(magic) == 12 && string(magic[4:8]) == "ftyp":
+		f, err := cr3.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		var exif, gps *tiff.IFD
+		if f.Exif != nil {
+			exif = f.Exif.IFD
+		}
+		if f.GPS != nil {
+			gps = f.GPS.IFD
+		}
+		return FromIFDs(f.IFD0.IFD, exif, gps), nil
+	default:
+		f, err := cr2.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return FromIFDs(f.IFDs[0], f.Exif, f.GPS), nil
+	}
+}
+
+// Gather the metadata from the IFD0, Exif and GPS directories of a file, any
+// of which may be nil.  This also reads the directories of CR2 and CR3 files.
+func FromIFDs(ifd0 *tiff.IFD, exif *tiff.IFD, gps *tiff.IFD) *Exif {
+	e := &Exif{
+		Make:                 text(ifd0, tiff.Make),
+		Model:                text(ifd0, tiff.Model),
+		Orientation:          integer(ifd0, tiff.Orientation),
+		SerialNumber:         text(exif, tiff.BodySerialNumber),
+		LensModel:            text(exif, tiff.LensModel),
+		LensSerialNumber:     text(exif, tiff.LensSerialNumber),
+		ExposureTime:         number(exif, tiff.ExposureTime),
+		FNumber:              number(exif, tiff.FNumber),
+		ISO:                  integer(exif, tiff.ISOSpeedRatings),
+		ExposureCompensation: number(exif, tiff.ExposureBiasValue),
+		ExposureProgram:      integer(exif, tiff.ExposureProgram),
+		MeteringMode:         integer(exif, tiff.MeteringMode),
+		Flash:                integer(exif, tiff.Flash),
+		FocalLength:          number(exif, tiff.FocalLength),
+		FocalLength35mm:      integer(exif, tiff.FocalLengthIn35mmFilm),
+		Width:                integer(exif, tiff.PixelXDimension),
+		Height:               integer(exif, tiff.PixelYDimension),
+		GPS:                  decodeGPS(gps),
+	}
+	if e.Orientation == 0 {
+		e.Orientation = 1
+	}
+
+	taken := text(exif, tiff.DateTimeOriginal)
+	if taken == "" {
+		taken = text(ifd0, tiff.DateTime)
+	}
+	e.Time = tiff.ParseTime(taken, text(exif, tiff.SubSecTimeOriginal), text(exif, tiff.OffsetTimeOriginal))
+	return e
+}
+
+// Exposure time the way cameras show it, such as "1/250" or "2.5"
+func (e *Exif) ShutterSpeed() string {
+	if e.ExposureTime <= 0 {
+		return ""
+	}
+	if e.ExposureTime < 0.5 {
+		return fmt.Sprintf("1/%d", int(math.Round(1/e.ExposureTime)))
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", e.ExposureTime), ".0")
+}
+
+func decodeGPS(ifd *tiff.IFD) *GPS {
+	latitude, err := degrees(ifd.Find(GPSLatitude))
+	if err != nil {
+		return nil
+	}
+	longitude, err := degrees(ifd.Find(GPSLongitude))
+	if err != nil {
+		return nil
+	}
+	if text(ifd, GPSLatitudeRef) == "S" {
+		latitude = -latitude
+	}
+	if text(ifd, GPSLongitudeRef) == "W" {
+		longitude = -longitude
+	}
+
+	gps := &GPS{Latitude: latitude, Longitude: longitude}
+	if entry := ifd.Find(GPSAltitude); entry != nil {
+		if altitude, err := entry.Float(0); err == nil {
+			gps.Altitude, gps.HasAltitude = altitude, true
+			if integer(ifd, GPSAltitudeRef) == 1 {
+				gps.Altitude = -gps.Altitude
+			}
+		}
+	}
+
+	date := ifd.Find(GPSDateStamp)
+	clock := ifd.Find(GPSTimeStamp)
+	if date != nil && clock != nil && clock.Count == 3 {
+		day, err := time.Parse("2006:01:02", date.String())
+		hours, _ := clock.Float(0)
+		minutes, _ := clock.Float(1)
+		seconds, _ := clock.Float(2)
+		if err == nil {
+			gps.Time = day.Add(time.Duration((hours*3600 + minutes*60 + seconds) * float64(time.Second)))
+		}
+	}
+	return gps
+}
+
+// Convert degrees, minutes and seconds to degrees
+func degrees(entry *tiff.Entry) (float64, error) {
+	if entry == nil || entry.Count != 3 {
+		return 0, errors.New("exif: missing coordinate")
+	}
+	var result float64
+	for i, scale := range []float64{1, 60, 3600} {
+		value, err := entry.Float(i)
+		if err != nil {
+			return 0, err
+		}
+		result += value / scale
+	}
+	return result, nil
+}
+
+func text(ifd *tiff.IFD, tag uint16) string {
+	if entry := ifd.Find(tag); entry != nil && entry.Type == tiff.ASCII {
+		return entry.String()
+	}
+	return ""
+}
+
+func integer(ifd *tiff.IFD, tag uint16) int {
+	if entry := ifd.Find(tag); entry != nil {
+		if value, err := entry.Int(0); err == nil {
+			return int(value)
+		}
+	}
+	return 0
+}
+
+func number(ifd *tiff.IFD, tag uint16) float64 {
+	if entry := ifd.Find(tag); entry != nil {
+		if value, err := entry.Float(0); err == nil {
+			return value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+func testJPEG(tiffData []byte) []byte {
+	app1 := append([]byte("Exif\x00\x00"), tiffData...)
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}
+	jpeg = append(jpeg, app1...)
+	return append(jpeg, 0xFF, 0xDA, 0x00, 0x02, 0x00, 0xFF, 0xD9)
+}
+
+func buildTestJPEG(order binary.ByteOrder, offset string, withGPS bool) []byte {
+	b := tifftest.New(order)
+	exifEntries := []tifftest.Entry{
+		b.Rational(tiff.ExposureTime, 1, 250),
+		b.Rational(tiff.FNumber, 8, 1),
+		b.Short(tiff.ExposureProgram, 3),
+		b.Short(tiff.ISOSpeedRatings, 400),
+		b.ASCII(tiff.DateTimeOriginal, "2015:06:01 12:30:45"),
+		b.SRational(tiff.ExposureBiasValue, -1, 3),
+		b.Rational(tiff.FocalLength, 70, 1),
+		b.ASCII(tiff.SubSecTimeOriginal, "25"),
+		b.Long(tiff.PixelXDimension, 5760),
+		b.Long(tiff.PixelYDimension, 3840),
+		b.ASCII(tiff.BodySerialNumber, "012345678901"),
+		b.ASCII(tiff.LensModel, "EF70-200mm f/2.8L IS II USM"),
+	}
+	if offset != "" {
+		exifEntries = append(exifEntries, b.ASCII(tiff.OffsetTimeOriginal, offset))
+	}
+	exif := b.IFD(exifEntries, 0)
+
+	ifd0 := []tifftest.Entry{
+		b.ASCII(tiff.Make, "Canon"),
+		b.ASCII(tiff.Model, "Canon EOS 5D Mark III"),
+		b.Short(tiff.Orientation, 8),
+		b.Long(tiff.ExifIFDPointer, exif),
+	}
+	if withGPS {
+		gps := b.IFD([]tifftest.Entry{
+			b.Byte(GPSVersionID, 2, 3, 0, 0),
+			b.ASCII(GPSLatitudeRef, "S"),
+			b.Rational(GPSLatitude, 33, 1, 51, 1, 3540, 100),
+			b.ASCII(GPSLongitudeRef, "E"),
+			b.Rational(GPSLongitude, 151, 1, 12, 1, 3000, 100),
+			b.Byte(GPSAltitudeRef, 1),
+			b.Rational(GPSAltitude, 15, 2),
+			b.Rational(GPSTimeStamp, 2, 1, 30, 1, 44, 1),
+			b.ASCII(GPSDateStamp, "2015:06:01"),
+		}, 0)
+		ifd0 = append(ifd0, b.Long(tiff.GPSIFDPointer, gps))
+	}
+	b.SetFirst(b.IFD(ifd0, 0))
+	return testJPEG(b.Bytes())
+}
+
+func TestDecode(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		e, err := Decode(bytes.NewReader(buildTestJPEG(order, "", false)))
+		assert.Nil(t, err)
+
+		assert.Equal(t, "Canon", e.Make)
+		assert.Equal(t, "Canon EOS 5D Mark III", e.Model)
+		assert.Equal(t, "012345678901", e.SerialNumber)
+		assert.Equal(t, "EF70-200mm f/2.8L IS II USM", e.LensModel)
+		assert.Equal(t, 0.004, e.ExposureTime)
+		assert.Equal(t, "1/250", e.ShutterSpeed())
+		assert.Equal(t, 8.0, e.FNumber)
+		assert.Equal(t, 400, e.ISO)
+		assert.InDelta(t, -0.333, e.ExposureCompensation, 0.001)
+		assert.Equal(t, 3, e.ExposureProgram)
+		assert.Equal(t, 70.0, e.FocalLength)
+		assert.Equal(t, 8, e.Orientation)
+		assert.Equal(t, 5760, e.Width)
+		assert.Equal(t, 3840, e.Height)
+		assert.Equal(t, time.Date(2015, 6, 1, 12, 30, 45, 250000000, time.Local), e.Time)
+		assert.Nil(t, e.GPS)
+	}
+}
+
+func TestTimeOffset(t *testing.T) {
+	e, err := Decode(bytes.NewReader(buildTestJPEG(binary.LittleEndian, "+10:00", false)))
+	assert.Nil(t, err)
+	assert.True(t, e.Time.Equal(time.Date(2015, 6, 1, 2, 30, 45, 250000000, time.UTC)))
+	_, seconds := e.Time.Zone()
+	assert.Equal(t, 10*3600, seconds)
+}
+
+func TestTimeCameraLocation(t *testing.T) {
+	defer func(location *time.Location) { tiff.CameraLocation = location }(tiff.CameraLocation)
+	tiff.CameraLocation = time.FixedZone("+10:00", 10*3600)
+
+	e, err := Decode(bytes.NewReader(buildTestJPEG(binary.LittleEndian, "", false)))
+	assert.Nil(t, err)
+	assert.True(t, e.Time.Equal(time.Date(2015, 6, 1, 2, 30, 45, 250000000, time.UTC)))
+
+	// a recorded offset wins
+	e, _ = Decode(bytes.NewReader(buildTestJPEG(binary.LittleEndian, "-05:00", false)))
+	assert.True(t, e.Time.Equal(time.Date(2015, 6, 1, 17, 30, 45, 250000000, time.UTC)))
+}
+
+func TestGPS(t *testing.T) {
+	e, err := Decode(bytes.NewReader(buildTestJPEG(binary.BigEndian, "", true)))
+	assert.Nil(t, err)
+
+	assert.NotNil(t, e.GPS)
+	assert.InDelta(t, -33.8598, e.GPS.Latitude, 0.0001)
+	assert.InDelta(t, 151.2083, e.GPS.Longitude, 0.0001)
+	assert.True(t, e.GPS.HasAltitude)
+	assert.Equal(t, -7.5, e.GPS.Altitude)
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 30, 44, 0, time.UTC), e.GPS.Time)
+}
+
+func TestShutterSpeed(t *testing.T) {
+	assert.Equal(t, "", (&Exif{}).ShutterSpeed())
+	assert.Equal(t, "1/3", (&Exif{ExposureTime: 0.3}).ShutterSpeed())
+	assert.Equal(t, "0.5", (&Exif{ExposureTime: 0.5}).ShutterSpeed())
+	assert.Equal(t, "30", (&Exif{ExposureTime: 30}).ShutterSpeed())
+}
+
+func TestDecodeWithoutExif(t *testing.T) {
+	_, err := Decode(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}))
+	assert.Equal(t, tiff.ErrNoExif, err)
+}
+
+func TestRead(t *testing.T) {
+	e, err := Read(bytes.NewReader(buildTestJPEG(binary.LittleEndian, "", true)))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, e.ISO)
+	assert.NotNil(t, e.GPS)
+
+	_, err = Read(bytes.NewReader([]byte("not an image")))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,256 @@
+package geotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/urlgrey/canon-eos-go/exif"
+	"github.com/urlgrey/canon-eos-go/tiff"
+	"github.com/urlgrey/canon-eos-go/xmp"
+)
+
+// Largest gap between track points interpolated across if MaxGap isn't set
+const DefaultMaxGap = 5 * time.Minute
+
+// Largest APP1 segment a JPEG can hold
+const maxSegmentLength = 0xFFFF
+
+// Places pictures on a track
+type Geotagger struct {
+	Track *Track
+
+	// Added to capture times to correct the camera's clock, so a camera
+	// running 30 seconds slow needs an offset of 30 seconds.  Capture times
+	// without a recorded time zone are taken to be in the local one; a
+	// camera set to another zone needs the difference added here too.
+	ClockOffset time.Duration
+
+	// Largest gap between track points to interpolate across, DefaultMaxGap
+	// if zero
+	MaxGap time.Duration
+}
+
+// Find where a picture captured at the time, by the camera's clock, was taken
+func (g *Geotagger) Locate(captured time.Time) (Point, error) {
+	maxGap := g.MaxGap
+	if maxGap == 0 {
+		maxGap = DefaultMaxGap
+	}
+	return g.Track.Locate(captured.Add(g.ClockOffset), maxGap)
+}
+
+// Read the capture time of a JPEG, CR2 or CR3 file
+func CaptureTime(name string) (time.Time, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	metadata, err := exif.Read(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if metadata.Time.IsZero() {
+		return time.Time{}, errors.New(fmt.Sprintf("geotag: %s has no capture time", name))
+	}
+	return metadata.Time, nil
+}
+
+// Set the position of a sidecar from the capture time of its picture
+func (g *Geotagger) TagSidecar(sidecar *xmp.Sidecar, captured time.Time) error {
+	point, err := g.Locate(captured)
+	if err != nil {
+		return err
+	}
+	sidecar.GPS = point.gps()
+	return nil
+}
+
+// Write the position into the Exif data of a JPEG file, replacing any
+// position already there
+func (g *Geotagger) TagJPEG(name string) error {
+	captured, err := CaptureTime(name)
+	if err != nil {
+		return err
+	}
+	point, err := g.Locate(captured)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	tagged, err := EmbedGPS(data, point.gps())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".part", tagged, 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".part", name)
+}
+
+func (p Point) gps() *exif.GPS {
+	return &exif.GPS{
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+		Altitude:    p.Elevation,
+		HasAltitude: p.HasElevation,
+		Time:        p.Time.UTC(),
+	}
+}
+
+// Return a copy of a JPEG with a GPS IFD holding the position.  The new GPS
+// IFD and a copy of IFD0 pointing at it are appended to the Exif data, so
+// existing offsets, such as those in the maker note, stay valid.  A JPEG
+// without Exif data gets a new APP1 segment.
+func EmbedGPS(jpeg []byte, gps *exif.GPS) ([]byte, error) {
+	start, length, err := tiff.FindJPEGExif(bytes.NewReader(jpeg))
+	if err == tiff.ErrNoExif {
+		return insertExif(jpeg, gps)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	structure := append([]byte(nil), jpeg[start:start+length]...)
+	reader, err := tiff.NewReader(bytes.NewReader(structure))
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := reader.ReadIFD(reader.First)
+	if err != nil {
+		return nil, err
+	}
+	structure = appendGPS(structure, reader.ByteOrder, ifd0.Entries, ifd0.Next, gps)
+
+	segmentLength := 2 + 6 + len(structure)
+	if segmentLength > maxSegmentLength {
+		return nil, errors.New("geotag: Exif data is too large to add a position to")
+	}
+	// the segment's marker and length come before the Exif header
+	segment := start - 6 - 4
+	result := make([]byte, 0, len(jpeg)+segmentLength)
+	result = append(result, jpeg[:segment]...)
+	result = append(result, 0xFF, 0xE1, byte(segmentLength>>8), byte(segmentLength))
+	result = append(result, "Exif\x00\x00"...)
+	result = append(result, structure...)
+	return append(result, jpeg[start+length:]...), nil
+}
+
+// Add a new APP1 segment holding just a GPS IFD, after the JFIF segment if
+// there is one, as that must come first
+func insertExif(jpeg []byte, gps *exif.GPS) ([]byte, error) {
+	order := binary.LittleEndian
+	structure := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	structure = appendGPS(structure, order, nil, 0, gps)
+
+	at := 2
+	if len(jpeg) >= 6 && jpeg[2] == 0xFF && jpeg[3] == 0xE0 {
+		at = 4 + (int(jpeg[4])<<8 | int(jpeg[5]))
+	}
+	if at > len(jpeg) {
+		return nil, tiff.ErrFormat
+	}
+
+	segmentLength := 2 + 6 + len(structure)
+	result := make([]byte, 0, len(jpeg)+2+segmentLength)
+	result = append(result, jpeg[:at]...)
+	result = append(result, 0xFF, 0xE1, byte(segmentLength>>8), byte(segmentLength))
+	result = append(result, "Exif\x00\x00"...)
+	result = append(result, structure...)
+	return append(result, jpeg[at:]...), nil
+}
+
+// Append a GPS IFD and an IFD0 with the entries given plus a pointer to it,
+// pointing the header at the new IFD0
+func appendGPS(structure []byte, order binary.ByteOrder, entries []*tiff.Entry, next uint32, gps *exif.GPS) []byte {
+	if len(structure)%2 == 1 {
+		structure = append(structure, 0)
+	}
+	gpsOffset := uint32(len(structure))
+	structure = append(structure, tiff.EncodeIFD(order, gpsOffset, gpsEntries(order, gps), 0)...)
+
+	pointer := make([]byte, 4)
+	order.PutUint32(pointer, gpsOffset)
+	ifd0 := []*tiff.Entry{tiff.NewEntry(order, tiff.GPSIFDPointer, tiff.Long, 1, pointer)}
+	for _, entry := range entries {
+		if entry.Tag != tiff.GPSIFDPointer {
+			ifd0 = append(ifd0, entry)
+		}
+	}
+	sort.SliceStable(ifd0, func(i, j int) bool {
+		return ifd0[i].Tag < ifd0[j].Tag
+	})
+
+	if len(structure)%2 == 1 {
+		structure = append(structure, 0)
+	}
+	ifd0Offset := uint32(len(structure))
+	structure = append(structure, tiff.EncodeIFD(order, ifd0Offset, ifd0, next)...)
+	order.PutUint32(structure[4:], ifd0Offset)
+	return structure
+}
+
+func gpsEntries(order binary.ByteOrder, gps *exif.GPS) []*tiff.Entry {
+	latitudeRef, longitudeRef := "N", "E"
+	latitude, longitude := gps.Latitude, gps.Longitude
+	if latitude < 0 {
+		latitudeRef, latitude = "S", -latitude
+	}
+	if longitude < 0 {
+		longitudeRef, longitude = "W", -longitude
+	}
+
+	entries := []*tiff.Entry{
+		tiff.NewEntry(order, exif.GPSVersionID, tiff.Byte, 4, []byte{2, 3, 0, 0}),
+		tiff.NewEntry(order, exif.GPSLatitudeRef, tiff.ASCII, 2, []byte(latitudeRef+"\x00")),
+		tiff.NewEntry(order, exif.GPSLatitude, tiff.Rational, 3, degreesMinutesSeconds(order, latitude)),
+		tiff.NewEntry(order, exif.GPSLongitudeRef, tiff.ASCII, 2, []byte(longitudeRef+"\x00")),
+		tiff.NewEntry(order, exif.GPSLongitude, tiff.Rational, 3, degreesMinutesSeconds(order, longitude)),
+	}
+	if gps.HasAltitude {
+		ref := byte(0)
+		if gps.Altitude < 0 {
+			ref = 1
+		}
+		entries = append(entries,
+			tiff.NewEntry(order, exif.GPSAltitudeRef, tiff.Byte, 1, []byte{ref}),
+			tiff.NewEntry(order, exif.GPSAltitude, tiff.Rational, 1, rational(order, math.Abs(gps.Altitude), 100)))
+	}
+	if !gps.Time.IsZero() {
+		fix := gps.Time.UTC()
+		clock := append(append(rational(order, float64(fix.Hour()), 1),
+			rational(order, float64(fix.Minute()), 1)...),
+			rational(order, float64(fix.Second())+float64(fix.Nanosecond())/1e9, 1000)...)
+		entries = append(entries,
+			tiff.NewEntry(order, exif.GPSTimeStamp, tiff.Rational, 3, clock),
+			tiff.NewEntry(order, exif.GPSDateStamp, tiff.ASCII, 11, []byte(fix.Format("2006:01:02")+"\x00")))
+	}
+	return entries
+}
+
+// Encode degrees as whole degrees and minutes and seconds to a thousandth
+func degreesMinutesSeconds(order binary.ByteOrder, value float64) []byte {
+	degrees := math.Floor(value)
+	minutes := math.Floor((value - degrees) * 60)
+	seconds := ((value-degrees)*60 - minutes) * 60
+	return append(append(rational(order, degrees, 1), rational(order, minutes, 1)...), rational(order, seconds, 1000)...)
+}
+
+// Encode a value as a rational with the given denominator
+func rational(order binary.ByteOrder, value float64, denominator uint32) []byte {
+	data := make([]byte, 8)
+	order.PutUint32(data, uint32(math.Round(value*float64(denominator))))
+	order.PutUint32(data[4:], denominator)
+	return data
+}
@@ -0,0 +1,152 @@
+package geotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/exif"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+	"github.com/urlgrey/canon-eos-go/xmp"
+)
+
+var testSOS = []byte{0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9}
+
+// A JPEG taken at 12:30:15 on a camera set to UTC+10, whose maker note
+// refers to a value by its offset
+func buildTestJPEG() []byte {
+	b := tifftest.New(binary.BigEndian)
+	makerNote := b.IFD([]tifftest.Entry{b.ASCII(0x0006, "Canon EOS 5D Mark III")}, 0)
+	exifIFD := b.IFD([]tifftest.Entry{
+		b.ASCII(tiff.DateTimeOriginal, "2015:06:01 12:30:15"),
+		b.ASCII(tiff.OffsetTimeOriginal, "+10:00"),
+		b.Undefined(tiff.MakerNote, append([]byte(nil), b.Bytes()[makerNote:]...)),
+	}, 0)
+	thumbnail := b.IFD([]tifftest.Entry{b.Long(tiff.JPEGInterchangeFormat, 0)}, 0)
+	b.SetFirst(b.IFD([]tifftest.Entry{
+		b.ASCII(tiff.Make, "Canon"),
+		b.ASCII(tiff.Model, "Canon EOS 5D Mark III"),
+		b.Long(tiff.ExifIFDPointer, exifIFD),
+	}, thumbnail))
+
+	app1 := append([]byte("Exif\x00\x00"), b.Bytes()...)
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}
+	return append(append(jpeg, app1...), testSOS...)
+}
+
+func newTestGeotagger(t *testing.T) *Geotagger {
+	track, err := ReadGPX(strings.NewReader(testGPX))
+	assert.Nil(t, err)
+	return &Geotagger{Track: track, MaxGap: time.Minute}
+}
+
+func TestGeotaggerClockOffset(t *testing.T) {
+	g := newTestGeotagger(t)
+	captured := time.Date(2015, 6, 1, 12, 30, 0, 0, time.FixedZone("+10:00", 10*3600))
+
+	point, err := g.Locate(captured)
+	assert.Nil(t, err)
+	assert.Equal(t, -33.85, point.Latitude)
+
+	// the camera runs 15 seconds slow
+	g.ClockOffset = 15 * time.Second
+	point, err = g.Locate(captured)
+	assert.Nil(t, err)
+	assert.InDelta(t, -33.8525, point.Latitude, 0.00001)
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 30, 15, 0, time.UTC), point.Time.UTC())
+}
+
+func TestEmbedGPS(t *testing.T) {
+	jpeg := buildTestJPEG()
+	gps := &exif.GPS{Latitude: -33.8525, Longitude: 151.2075, Altitude: 17.5, HasAltitude: true, Time: time.Date(2015, 6, 1, 2, 30, 15, 0, time.UTC)}
+
+	tagged, err := EmbedGPS(jpeg, gps)
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasSuffix(tagged, testSOS))
+
+	metadata, err := exif.Decode(bytes.NewReader(tagged))
+	assert.Nil(t, err)
+	assert.Equal(t, "Canon EOS 5D Mark III", metadata.Model)
+	assert.NotNil(t, metadata.GPS)
+	assert.InDelta(t, gps.Latitude, metadata.GPS.Latitude, 0.000001)
+	assert.InDelta(t, gps.Longitude, metadata.GPS.Longitude, 0.000001)
+	assert.Equal(t, 17.5, metadata.GPS.Altitude)
+	assert.Equal(t, gps.Time, metadata.GPS.Time)
+
+	// the maker note and the thumbnail IFD are still reachable
+	reader, _ := tiff.NewJPEGReader(bytes.NewReader(tagged))
+	ifds, err := reader.ReadIFDs()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ifds))
+	exifIFD, _ := reader.ReadSubIFD(ifds[0], tiff.ExifIFDPointer)
+	makerNote, err := reader.ReadIFD(exifIFD.Find(tiff.MakerNote).Offset)
+	assert.Nil(t, err)
+	assert.Equal(t, "Canon EOS 5D Mark III", makerNote.Find(0x0006).String())
+
+	// tagging again replaces the position
+	gps.Latitude = 10
+	tagged, err = EmbedGPS(tagged, gps)
+	assert.Nil(t, err)
+	metadata, _ = exif.Decode(bytes.NewReader(tagged))
+	assert.InDelta(t, 10, metadata.GPS.Latitude, 0.000001)
+}
+
+func TestEmbedGPSTruncated(t *testing.T) {
+	jpeg := buildTestJPEG()
+	gps := &exif.GPS{Latitude: -33.8525, Longitude: 151.2075}
+
+	_, err := EmbedGPS(jpeg[:100], gps)
+	assert.Equal(t, tiff.ErrFormat, err)
+
+	// cut short anywhere, the file is rejected rather than read past its end
+	for length := 0; length < len(jpeg)-len(testSOS); length++ {
+		assert.NotPanics(t, func() { EmbedGPS(jpeg[:length], gps) }, "length %d", length)
+	}
+}
+
+func TestEmbedGPSWithoutExif(t *testing.T) {
+	jfif := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00}
+	jpeg := append(append([]byte(nil), jfif...), testSOS...)
+
+	tagged, err := EmbedGPS(jpeg, &exif.GPS{Latitude: 51.5, Longitude: -0.125})
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(tagged, jfif))
+
+	metadata, err := exif.Decode(bytes.NewReader(tagged))
+	assert.Nil(t, err)
+	assert.InDelta(t, 51.5, metadata.GPS.Latitude, 0.000001)
+	assert.InDelta(t, -0.125, metadata.GPS.Longitude, 0.000001)
+	assert.False(t, metadata.GPS.HasAltitude)
+}
+
+func TestTagJPEG(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	assert.Nil(t, os.WriteFile(name, buildTestJPEG(), 0644))
+
+	assert.Nil(t, newTestGeotagger(t).TagJPEG(name))
+	data, _ := os.ReadFile(name)
+	metadata, err := exif.Decode(bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.InDelta(t, -33.8525, metadata.GPS.Latitude, 0.00001)
+	assert.True(t, metadata.GPS.HasAltitude)
+
+	// an hour later the track has ended
+	g := newTestGeotagger(t)
+	g.ClockOffset = 2 * time.Hour
+	assert.Equal(t, ErrNoPosition, g.TagJPEG(name))
+}
+
+func TestTagSidecar(t *testing.T) {
+	sidecar := &xmp.Sidecar{Rating: 2}
+	captured := time.Date(2015, 6, 1, 2, 30, 30, 0, time.UTC)
+	assert.Nil(t, newTestGeotagger(t).TagSidecar(sidecar, captured))
+	assert.NotNil(t, sidecar.GPS)
+	assert.InDelta(t, -33.855, sidecar.GPS.Latitude, 0.00001)
+	assert.Equal(t, captured, sidecar.GPS.Time)
+}
@@ -0,0 +1,267 @@
+// Package geotag finds where pictures were taken by matching their capture
+// times against a track recorded by a GPS logger, and writes the positions
+// into XMP sidecars or the Exif data of JPEG files.
+package geotag
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A position recorded by the logger
+type Point struct {
+	Time time.Time
+
+	// Degrees, negative south of the equator and west of Greenwich
+	Latitude  float64
+	Longitude float64
+
+	// Metres above sea level
+	Elevation    float64
+	HasElevation bool
+}
+
+// Points recorded by a logger, in time order
+type Track struct {
+	Points []Point
+}
+
+// Create a track from points in any order
+func NewTrack(points []Point) *Track {
+	sorted := append([]Point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	return &Track{Points: sorted}
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Latitude  float64  `xml:"lat,attr"`
+	Longitude float64  `xml:"lon,attr"`
+	Elevation *float64 `xml:"ele"`
+	Time      string   `xml:"time"`
+}
+
+// Read the track points of a GPX file.  Points without a time are skipped,
+// as they can't be matched to pictures.
+func ReadGPX(r io.Reader) (*Track, error) {
+	var file gpxFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, errors.New(fmt.Sprintf("geotag: reading GPX: %s", err))
+	}
+
+	var points []Point
+	for _, track := range file.Tracks {
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				recorded, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time))
+				if err != nil {
+					continue
+				}
+				point := Point{Time: recorded, Latitude: p.Latitude, Longitude: p.Longitude}
+				if p.Elevation != nil {
+					point.Elevation, point.HasElevation = *p.Elevation, true
+				}
+				points = append(points, point)
+			}
+		}
+	}
+	if len(points) == 0 {
+		return nil, errors.New("geotag: GPX file has no timed track points")
+	}
+	return NewTrack(points), nil
+}
+
+// Read the positions of an NMEA 0183 log.  RMC sentences give the date, time
+// and position; GGA sentences add the altitude.  Sentences with a bad
+// checksum or without a fix are skipped.
+func ReadNMEA(r io.Reader) (*Track, error) {
+	var points []Point
+	var date time.Time
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields, ok := parseSentence(scanner.Text())
+		if !ok || len(fields[0]) != 5 {
+			continue
+		}
+
+		var point Point
+		switch fields[0][2:] {
+		case "RMC":
+			// time, status, latitude, N/S, longitude, E/W, speed, course, date
+			if len(fields) < 10 || fields[2] != "A" {
+				continue
+			}
+			day, err := time.Parse("020106", fields[9])
+			if err != nil {
+				continue
+			}
+			date = day
+			if point, ok = parsePosition(date, fields[1], fields[3:7]); !ok {
+				continue
+			}
+		case "GGA":
+			// time, latitude, N/S, longitude, E/W, quality, satellites,
+			// dilution, altitude
+			if len(fields) < 10 || fields[6] == "0" || date.IsZero() {
+				continue
+			}
+			if point, ok = parsePosition(date, fields[1], fields[2:6]); !ok {
+				continue
+			}
+			if elevation, err := strconv.ParseFloat(fields[9], 64); err == nil {
+				point.Elevation, point.HasElevation = elevation, true
+			}
+		default:
+			continue
+		}
+
+		// a logger writes several sentences for each fix
+		if last := len(points) - 1; last >= 0 && points[last].Time.Equal(point.Time) {
+			if point.HasElevation {
+				points[last].Elevation, points[last].HasElevation = point.Elevation, true
+			}
+			continue
+		}
+		points = append(points, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, errors.New("geotag: NMEA log has no positions")
+	}
+	return NewTrack(points), nil
+}
+
+// Split a sentence such as "$GPRMC,...*6A" into its fields, checking the
+// checksum if there is one
+func parseSentence(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, false
+	}
+	line = line[1:]
+	if star := strings.LastIndexByte(line, '*'); star >= 0 {
+		expected, err := strconv.ParseUint(line[star+1:], 16, 8)
+		if err != nil {
+			return nil, false
+		}
+		var sum byte
+		for i := 0; i < star; i++ {
+			sum ^= line[i]
+		}
+		if sum != byte(expected) {
+			return nil, false
+		}
+		line = line[:star]
+	}
+	return strings.Split(line, ","), true
+}
+
+// Parse the time of day and the latitude, N/S, longitude and E/W fields
+func parsePosition(date time.Time, clock string, fields []string) (Point, bool) {
+	if len(clock) < 6 {
+		return Point{}, false
+	}
+	hours, err1 := strconv.Atoi(clock[0:2])
+	minutes, err2 := strconv.Atoi(clock[2:4])
+	seconds, err3 := strconv.ParseFloat(clock[4:], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return Point{}, false
+	}
+	latitude, ok := parseCoordinate(fields[0], fields[1], 2, "S")
+	if !ok {
+		return Point{}, false
+	}
+	longitude, ok := parseCoordinate(fields[2], fields[3], 3, "W")
+	if !ok {
+		return Point{}, false
+	}
+
+	offset := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+	return Point{Time: date.Add(offset), Latitude: latitude, Longitude: longitude}, true
+}
+
+// Parse a coordinate written as degrees and decimal minutes, such as
+// 3351.594 for 33°51.594'
+func parseCoordinate(value string, direction string, degreeDigits int, negative string) (float64, bool) {
+	if len(value) < degreeDigits+2 {
+		return 0, false
+	}
+	degrees, err := strconv.Atoi(value[:degreeDigits])
+	if err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil {
+		return 0, false
+	}
+	result := float64(degrees) + minutes/60
+	if direction == negative {
+		result = -result
+	}
+	return result, true
+}
+
+// Returned when a track has no position near a time
+var ErrNoPosition = errors.New("geotag: track has no position near the capture time")
+
+// Find the position at a time, interpolating between the points either side
+// of it.  Points further than maxGap apart aren't interpolated between, so a
+// logger that lost its fix, or was switched off, doesn't place pictures
+// somewhere between where it stopped and started again.
+func (t *Track) Locate(at time.Time, maxGap time.Duration) (Point, error) {
+	points := t.Points
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(at)
+	})
+
+	switch {
+	case i < len(points) && points[i].Time.Equal(at):
+		return points[i], nil
+	case i == 0:
+		if len(points) > 0 && points[0].Time.Sub(at) <= maxGap {
+			return points[0], nil
+		}
+		return Point{}, ErrNoPosition
+	case i == len(points):
+		if at.Sub(points[i-1].Time) <= maxGap {
+			return points[i-1], nil
+		}
+		return Point{}, ErrNoPosition
+	}
+
+	before, after := points[i-1], points[i]
+	span := after.Time.Sub(before.Time)
+	if span > maxGap {
+		return Point{}, ErrNoPosition
+	}
+	fraction := float64(at.Sub(before.Time)) / float64(span)
+	point := Point{
+		Time:      at,
+		Latitude:  before.Latitude + (after.Latitude-before.Latitude)*fraction,
+		Longitude: before.Longitude + (after.Longitude-before.Longitude)*fraction,
+	}
+	if before.HasElevation && after.HasElevation {
+		point.Elevation = before.Elevation + (after.Elevation-before.Elevation)*fraction
+		point.HasElevation = true
+	}
+	return point, nil
+}
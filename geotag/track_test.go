@@ -0,0 +1,93 @@
+package geotag
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="logger" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Morning</name>
+    <trkseg>
+      <trkpt lat="-33.8600" lon="151.2000"><ele>10.0</ele><time>2015-06-01T02:31:00Z</time></trkpt>
+      <trkpt lat="-33.8500" lon="151.2100"><ele>20.0</ele><time>2015-06-01T02:30:00Z</time></trkpt>
+      <trkpt lat="-33.8000" lon="151.3000"><time>2015-06-01T03:00:00Z</time></trkpt>
+      <trkpt lat="0" lon="0"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const testNMEA = `$GPGGA,023000.00,3351.0000,S,15112.6000,E,1,08,0.9,20.0,M,21.0,M,,*44
+$GPRMC,023000.00,A,3351.0000,S,15112.6000,E,0.0,0.0,010615,,,A*45
+$GPGGA,023000.00,3351.0000,S,15112.6000,E,1,08,0.9,20.0,M,21.0,M,,*44
+$GPRMC,023100.00,V,3351.6000,S,15112.0000,E,0.0,0.0,010615,,,A*53
+$GPRMC,023100.00,A,3351.6000,S,15112.0000,E,0.0,0.0,010615,,,A*44
+$GPRMC,023200.00,A,3351.6000,S,15112.0000,E,0.0,0.0,010615,,,A*12
+$GNRMC,023300.00,A,3352.0000,S,15112.0000,E,0.0,0.0,010615,,,A`
+
+func TestReadGPX(t *testing.T) {
+	track, err := ReadGPX(strings.NewReader(testGPX))
+	assert.Nil(t, err)
+
+	// sorted by time, skipping the point without one
+	assert.Equal(t, 3, len(track.Points))
+	assert.Equal(t, Point{Time: time.Date(2015, 6, 1, 2, 30, 0, 0, time.UTC), Latitude: -33.85, Longitude: 151.21, Elevation: 20, HasElevation: true}, track.Points[0])
+	assert.False(t, track.Points[2].HasElevation)
+
+	_, err = ReadGPX(strings.NewReader("<gpx></gpx>"))
+	assert.NotNil(t, err)
+}
+
+func TestReadNMEA(t *testing.T) {
+	track, err := ReadNMEA(strings.NewReader(testNMEA))
+	assert.Nil(t, err)
+
+	// the GGA before the first RMC has no date, the void fix and the bad
+	// checksum are skipped, and the GGA after the RMC adds its altitude
+	assert.Equal(t, 3, len(track.Points))
+	first := track.Points[0]
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 30, 0, 0, time.UTC), first.Time)
+	assert.InDelta(t, -33.85, first.Latitude, 0.00001)
+	assert.InDelta(t, 151.21, first.Longitude, 0.00001)
+	assert.True(t, first.HasElevation)
+	assert.Equal(t, 20.0, first.Elevation)
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 31, 0, 0, time.UTC), track.Points[1].Time)
+	assert.Equal(t, time.Date(2015, 6, 1, 2, 33, 0, 0, time.UTC), track.Points[2].Time)
+
+	_, err = ReadNMEA(strings.NewReader("$GPTXT,01,01,02,ANTSTATUS=OK*3B\n"))
+	assert.NotNil(t, err)
+}
+
+func TestLocate(t *testing.T) {
+	track, _ := ReadGPX(strings.NewReader(testGPX))
+	start := time.Date(2015, 6, 1, 2, 30, 0, 0, time.UTC)
+
+	point, err := track.Locate(start.Add(15*time.Second), time.Minute)
+	assert.Nil(t, err)
+	assert.InDelta(t, -33.8525, point.Latitude, 0.00001)
+	assert.InDelta(t, 151.2075, point.Longitude, 0.00001)
+	assert.Equal(t, 17.5, point.Elevation)
+
+	point, err = track.Locate(start, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, track.Points[0], point)
+
+	// either side of the track, within and beyond the gap
+	point, err = track.Locate(start.Add(-30*time.Second), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, track.Points[0], point)
+	_, err = track.Locate(start.Add(-2*time.Minute), time.Minute)
+	assert.Equal(t, ErrNoPosition, err)
+	_, err = track.Locate(start.Add(time.Hour), time.Minute)
+	assert.Equal(t, ErrNoPosition, err)
+
+	// the logger lost its fix for half an hour
+	_, err = track.Locate(start.Add(10*time.Minute), time.Minute)
+	assert.Equal(t, ErrNoPosition, err)
+	_, err = track.Locate(start.Add(10*time.Minute), time.Hour)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,174 @@
+// Package tifftest builds small TIFF structures for tests of the packages
+// that read them.
+package tifftest
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// A tag and its encoded values
+type Entry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	Data  []byte
+}
+
+// Lays out headers, IFDs and data blobs in a byte slice.  IFDs refer to data
+// written before them, so a chain is built from its last IFD backwards.
+type Builder struct {
+	Order binary.ByteOrder
+	data  []byte
+}
+
+// Create a Builder with a TIFF header pointing at no IFD yet
+func New(order binary.ByteOrder) *Builder {
+	b := &Builder{Order: order}
+	if order == binary.LittleEndian {
+		b.data = append(b.data, 'I', 'I')
+	} else {
+		b.data = append(b.data, 'M', 'M')
+	}
+	b.data = b.appendUint16(b.data, 42)
+	b.data = b.appendUint32(b.data, 0)
+	return b
+}
+
+// Create a Builder with no header, for maker notes and other headerless IFDs
+func NewHeaderless(order binary.ByteOrder) *Builder {
+	return &Builder{Order: order}
+}
+
+// Point the header at the first IFD
+func (b *Builder) SetFirst(offset uint32) {
+	b.PutUint32(4, offset)
+}
+
+// Overwrite four bytes at offset
+func (b *Builder) PutUint32(offset int, value uint32) {
+	b.Order.PutUint32(b.data[offset:], value)
+}
+
+// Append data at an even offset, returning the offset
+func (b *Builder) Append(data []byte) uint32 {
+	if len(b.data)%2 == 1 {
+		b.data = append(b.data, 0)
+	}
+	offset := uint32(len(b.data))
+	b.data = append(b.data, data...)
+	return offset
+}
+
+// Write an IFD with its values, returning its offset
+func (b *Builder) IFD(entries []Entry, next uint32) uint32 {
+	// values too large for the entries go in front of the IFD
+	values := make([]uint32, len(entries))
+	for i, entry := range entries {
+		if len(entry.Data) > 4 {
+			values[i] = b.Append(entry.Data)
+		}
+	}
+
+	ifd := b.appendUint16(nil, uint16(len(entries)))
+	for i, entry := range entries {
+		ifd = b.appendUint16(ifd, entry.Tag)
+		ifd = b.appendUint16(ifd, entry.Type)
+		ifd = b.appendUint32(ifd, entry.Count)
+		if len(entry.Data) > 4 {
+			ifd = b.appendUint32(ifd, values[i])
+		} else {
+			field := make([]byte, 4)
+			copy(field, entry.Data)
+			ifd = append(ifd, field...)
+		}
+	}
+	ifd = b.appendUint32(ifd, next)
+	return b.Append(ifd)
+}
+
+// Everything written so far
+func (b *Builder) Bytes() []byte {
+	return b.data
+}
+
+func (b *Builder) Byte(tag uint16, values ...byte) Entry {
+	return Entry{Tag: tag, Type: 1, Count: uint32(len(values)), Data: values}
+}
+
+// An ASCII entry with its terminating NUL
+func (b *Builder) ASCII(tag uint16, value string) Entry {
+	return Entry{Tag: tag, Type: 2, Count: uint32(len(value) + 1), Data: append([]byte(value), 0)}
+}
+
+func (b *Builder) Short(tag uint16, values ...uint16) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint16(data, value)
+	}
+	return Entry{Tag: tag, Type: 3, Count: uint32(len(values)), Data: data}
+}
+
+func (b *Builder) Long(tag uint16, values ...uint32) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint32(data, value)
+	}
+	return Entry{Tag: tag, Type: 4, Count: uint32(len(values)), Data: data}
+}
+
+// A rational entry from numerator and denominator pairs
+func (b *Builder) Rational(tag uint16, values ...uint32) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint32(data, value)
+	}
+	return Entry{Tag: tag, Type: 5, Count: uint32(len(values) / 2), Data: data}
+}
+
+// A signed rational entry from numerator and denominator pairs
+func (b *Builder) SRational(tag uint16, values ...int32) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint32(data, uint32(value))
+	}
+	return Entry{Tag: tag, Type: 10, Count: uint32(len(values) / 2), Data: data}
+}
+
+func (b *Builder) SShort(tag uint16, values ...int16) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint16(data, uint16(value))
+	}
+	return Entry{Tag: tag, Type: 8, Count: uint32(len(values)), Data: data}
+}
+
+func (b *Builder) Double(tag uint16, values ...float64) Entry {
+	var data []byte
+	for _, value := range values {
+		data = b.appendUint64(data, math.Float64bits(value))
+	}
+	return Entry{Tag: tag, Type: 12, Count: uint32(len(values)), Data: data}
+}
+
+func (b *Builder) Undefined(tag uint16, data []byte) Entry {
+	return Entry{Tag: tag, Type: 7, Count: uint32(len(data)), Data: data}
+}
+
+func (b *Builder) appendUint16(data []byte, value uint16) []byte {
+	field := make([]byte, 2)
+	b.Order.PutUint16(field, value)
+	return append(data, field...)
+}
+
+func (b *Builder) appendUint32(data []byte, value uint32) []byte {
+	field := make([]byte, 4)
+	b.Order.PutUint32(field, value)
+	return append(data, field...)
+}
+
+func (b *Builder) appendUint64(data []byte, value uint64) []byte {
+	field := make([]byte, 8)
+	b.Order.PutUint64(field, value)
+	return append(data, field...)
+}
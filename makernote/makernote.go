@@ -0,0 +1,342 @@
+// Package makernote decodes the Canon maker note found in the Exif data of
+// JPEG, CR2 and CR3 files: lens, focus distance, AF points, serial numbers,
+// picture style and camera temperature.
+//
+// The shutter count is not supported.  Current bodies only record it in the
+// CameraInfo data, whose layout differs for every model and isn't
+// documented, and reading it from the wrong place gives a plausible but
+// wrong count.
+package makernote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/urlgrey/canon-eos-go/cr2"
+	"github.com/urlgrey/canon-eos-go/cr3"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// Canon maker note tags
+const (
+	TagCameraSettings       uint16 = 0x0001
+	TagShotInfo             uint16 = 0x0004
+	TagImageType            uint16 = 0x0006
+	TagFirmwareVersion      uint16 = 0x0007
+	TagOwnerName            uint16 = 0x0009
+	TagSerialNumber         uint16 = 0x000C
+	TagModelID              uint16 = 0x0010
+	TagAFInfo2              uint16 = 0x0026
+	TagFileInfo             uint16 = 0x0093
+	TagLensModel            uint16 = 0x0095
+	TagInternalSerialNumber uint16 = 0x0096
+	TagProcessingInfo       uint16 = 0x00A0
+)
+
+// Returned when a file has no Canon maker note
+var ErrNoMakerNote = errors.New("makernote: file has no maker note")
+
+// Picture styles, as recorded in the processing information
+type PictureStyle int
+
+const (
+	PictureStyleNone         PictureStyle = 0x00
+	PictureStyleUserDefined1 PictureStyle = 0x21
+	PictureStyleUserDefined2 PictureStyle = 0x22
+	PictureStyleUserDefined3 PictureStyle = 0x23
+	PictureStyleStandard     PictureStyle = 0x81
+	PictureStylePortrait     PictureStyle = 0x82
+	PictureStyleLandscape    PictureStyle = 0x83
+	PictureStyleNeutral      PictureStyle = 0x84
+	PictureStyleFaithful     PictureStyle = 0x85
+	PictureStyleMonochrome   PictureStyle = 0x86
+	PictureStyleAuto         PictureStyle = 0x87
+	PictureStyleFineDetail   PictureStyle = 0x88
+)
+
+var pictureStyleNames = map[PictureStyle]string{
+	PictureStyleNone:         "None",
+	PictureStyleUserDefined1: "User Def. 1",
+	PictureStyleUserDefined2: "User Def. 2",
+	PictureStyleUserDefined3: "User Def. 3",
+	PictureStyleStandard:     "Standard",
+	PictureStylePortrait:     "Portrait",
+	PictureStyleLandscape:    "Landscape",
+	PictureStyleNeutral:      "Neutral",
+	PictureStyleFaithful:     "Faithful",
+	PictureStyleMonochrome:   "Monochrome",
+	PictureStyleAuto:         "Auto",
+	PictureStyleFineDetail:   "Fine Detail",
+}
+
+func (p PictureStyle) String() string {
+	if name, ok := pictureStyleNames[p]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Lens fitted when the picture was taken
+type Lens struct {
+	// Canon's identifier for the lens model
+	Type int
+
+	// Name of the lens, empty for bodies that don't record it
+	Model string
+
+	// Focal length range in mm, the same for a prime lens
+	MinFocalLength float64
+	MaxFocalLength float64
+}
+
+// An AF point, its position and size in pixels of the AF image with the
+// origin at its centre, as the camera records them
+type AFPoint struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+
+	InFocus  bool
+	Selected bool
+}
+
+// AF points of the picture
+type AFInfo struct {
+	AreaMode int
+
+	// Size of the image the AF point positions refer to
+	ImageWidth  int
+	ImageHeight int
+
+	Points []AFPoint
+}
+
+// Points that were in focus
+func (a *AFInfo) InFocus() []AFPoint {
+	var points []AFPoint
+	for _, point := range a.Points {
+		if point.InFocus {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// A decoded maker note.  Fields the body doesn't record are left at their
+// zero values.
+type MakerNote struct {
+	// Model name, such as "Canon EOS 5D Mark III"
+	ImageType string
+
+	FirmwareVersion string
+	OwnerName       string
+	ModelID         uint32
+
+	// Serial number of the body, as printed on it, and Canon's internal
+	// serial number
+	SerialNumber         uint32
+	InternalSerialNumber string
+
+	Lens Lens
+
+	// Distance to the focus plane in metres, given as a range; the upper
+	// limit is +Inf when focused at infinity
+	FocusDistanceUpper float64
+	FocusDistanceLower float64
+
+	// Nil if the body doesn't record AF points in the newer AFInfo2 format
+	AF *AFInfo
+
+	PictureStyle PictureStyle
+
+	// Temperature of the body in degrees Celsius when the picture was taken
+	Temperature    int
+	HasTemperature bool
+}
+
+// Read the maker note of a JPEG, CR2 or CR3 file
+func Read(r io.ReaderAt) (*MakerNote, error) {
+	magic := make([]byte, 12)
+	n, _ := r.ReadAt(magic, 0)
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0xFF, 0xD8}):
+		return FromJPEG(r)
+	case len(magic) == 12 && string(magic[4:8]) == "ftyp":
+		f, err := cr3.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return FromCR3(f)
+	default:
+		f, err := cr2.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return FromCR2(f)
+	}
+}
+
+// Read the maker note of a JPEG file
+func FromJPEG(r io.ReaderAt) (*MakerNote, error) {
+	reader, err := tiff.NewJPEGReader(r)
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := reader.ReadIFD(reader.First)
+	if err != nil {
+		return nil, err
+	}
+	exif, err := reader.ReadSubIFD(ifd0, tiff.ExifIFDPointer)
+	if err != nil {
+		return nil, err
+	}
+	entry := exif.Find(tiff.MakerNote)
+	if entry == nil {
+		return nil, ErrNoMakerNote
+	}
+	ifd, err := reader.ReadIFD(entry.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(ifd), nil
+}
+
+// Decode the maker note of a CR2 file
+func FromCR2(f *cr2.File) (*MakerNote, error) {
+	if f.MakerNote == nil {
+		return nil, ErrNoMakerNote
+	}
+	return Decode(f.MakerNote), nil
+}
+
+// Decode the maker note of a CR3 file
+func FromCR3(f *cr3.File) (*MakerNote, error) {
+	if f.MakerNote == nil {
+		return nil, ErrNoMakerNote
+	}
+	return Decode(f.MakerNote.IFD), nil
+}
+
+// Decode a Canon maker note IFD.  Tags that are missing or malformed are
+// skipped.
+func Decode(ifd *tiff.IFD) *MakerNote {
+	m := &MakerNote{}
+	if entry := ifd.Find(TagImageType); entry != nil {
+		m.ImageType = entry.String()
+	}
+	if entry := ifd.Find(TagFirmwareVersion); entry != nil {
+		m.FirmwareVersion = entry.String()
+	}
+	if entry := ifd.Find(TagOwnerName); entry != nil {
+		m.OwnerName = entry.String()
+	}
+	if entry := ifd.Find(TagModelID); entry != nil {
+		m.ModelID, _ = entry.Uint(0)
+	}
+	if entry := ifd.Find(TagSerialNumber); entry != nil {
+		m.SerialNumber, _ = entry.Uint(0)
+	}
+	if entry := ifd.Find(TagInternalSerialNumber); entry != nil {
+		m.InternalSerialNumber = entry.String()
+	}
+	if entry := ifd.Find(TagLensModel); entry != nil {
+		m.Lens.Model = entry.String()
+	}
+
+	if settings := values(ifd, TagCameraSettings); len(settings) > 25 {
+		m.Lens.Type = settings[22]
+		// focal lengths are in units given by the next value
+		units := float64(settings[25])
+		if units == 0 {
+			units = 1
+		}
+		m.Lens.MaxFocalLength = float64(settings[23]) / units
+		m.Lens.MinFocalLength = float64(settings[24]) / units
+	}
+
+	if shot := values(ifd, TagShotInfo); len(shot) > 20 {
+		if shot[12] != 0 {
+			m.Temperature, m.HasTemperature = shot[12]-128, true
+		}
+		m.FocusDistanceUpper = focusDistance(shot[19])
+		m.FocusDistanceLower = focusDistance(shot[20])
+	}
+
+	if processing := values(ifd, TagProcessingInfo); len(processing) > 10 {
+		m.PictureStyle = PictureStyle(processing[10])
+	}
+	m.AF = decodeAFInfo2(ifd.Find(TagAFInfo2))
+	return m
+}
+
+// Values of an array of shorts, empty if the tag is missing
+func values(ifd *tiff.IFD, tag uint16) []int {
+	entry := ifd.Find(tag)
+	if entry == nil {
+		return nil
+	}
+	raw, err := entry.Uints()
+	if err != nil {
+		return nil
+	}
+	result := make([]int, len(raw))
+	for i, value := range raw {
+		result[i] = int(value)
+	}
+	return result
+}
+
+// Convert a focus distance in centimetres, where 0xFFFF means infinity
+func focusDistance(value int) float64 {
+	if value == 0xFFFF {
+		return math.Inf(1)
+	}
+	return float64(value) / 100
+}
+
+// Decode the AFInfo2 array: its size, the AF area mode, the number of points,
+// the number of valid points, the image and AF image sizes, then the widths,
+// heights, X and Y positions of each point and bit masks of the points in
+// focus and selected
+func decodeAFInfo2(entry *tiff.Entry) *AFInfo {
+	if entry == nil || entry.Type != tiff.Short {
+		return nil
+	}
+	raw := make([]int, entry.Count)
+	for i := range raw {
+		value, _ := entry.Int(i)
+		raw[i] = int(value)
+	}
+	if len(raw) < 8 {
+		return nil
+	}
+
+	count := raw[2]
+	masks := (count + 15) / 16
+	if 8+4*count+2*masks > len(raw) {
+		return nil
+	}
+	af := &AFInfo{AreaMode: raw[1], ImageWidth: raw[6], ImageHeight: raw[7]}
+	widths := raw[8:]
+	heights := raw[8+count:]
+	xs := raw[8+2*count:]
+	ys := raw[8+3*count:]
+	inFocus := raw[8+4*count:]
+	selected := raw[8+4*count+masks:]
+	for i := 0; i < count; i++ {
+		af.Points = append(af.Points, AFPoint{
+			X:        int(int16(xs[i])),
+			Y:        int(int16(ys[i])),
+			Width:    widths[i],
+			Height:   heights[i],
+			InFocus:  inFocus[i/16]&(1<<uint(i%16)) != 0,
+			Selected: selected[i/16]&(1<<uint(i%16)) != 0,
+		})
+	}
+	return af
+}
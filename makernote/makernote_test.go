@@ -0,0 +1,131 @@
+package makernote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+	"github.com/urlgrey/canon-eos-go/tiff"
+)
+
+// Array of shorts with the values given at their indexes, led by its size in
+// bytes as Canon writes them
+func shorts(length int, values map[int]uint16) []uint16 {
+	result := make([]uint16, length)
+	result[0] = uint16(length * 2)
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+func buildMakerNote(b *tifftest.Builder) uint32 {
+	af := []uint16{
+		0, 2, 3, 3, 5760, 3840, 5760, 3840,
+		// widths, heights, X and Y positions of three points
+		100, 100, 100,
+		120, 120, 120,
+		0, 0xFF9C, 100,
+		0, 50, 0xFFCE,
+		// in focus and selected masks
+		0x0005, 0x0001,
+	}
+	af[0] = uint16(len(af) * 2)
+
+	return b.IFD([]tifftest.Entry{
+		b.Short(TagCameraSettings, shorts(30, map[int]uint16{22: 61182, 23: 105, 24: 24, 25: 1})...),
+		b.Short(TagShotInfo, shorts(30, map[int]uint16{12: 158, 19: 0xFFFF, 20: 350})...),
+		b.ASCII(TagImageType, "Canon EOS 5D Mark III"),
+		b.ASCII(TagFirmwareVersion, "Firmware Version 1.3.5"),
+		b.ASCII(TagOwnerName, "Studio A"),
+		b.Long(TagSerialNumber, 123456789),
+		b.Long(TagModelID, 0x80000285),
+		b.Short(TagAFInfo2, af...),
+		b.Short(TagFileInfo, shorts(20, map[int]uint16{1: 4321})...),
+		b.ASCII(TagLensModel, "EF24-105mm f/4L IS USM"),
+		b.ASCII(TagInternalSerialNumber, "PA1234567"),
+		b.Short(TagProcessingInfo, shorts(15, map[int]uint16{10: 0x83})...),
+	}, 0)
+}
+
+func buildTestJPEG(withMakerNote bool) []byte {
+	b := tifftest.New(binary.LittleEndian)
+	entries := []tifftest.Entry{b.Short(tiff.ISOSpeedRatings, 100)}
+	if withMakerNote {
+		// the maker note is a copy of this IFD, its value offsets still
+		// pointing at the values written with the original
+		makerNote := buildMakerNote(b)
+		entries = append(entries, b.Undefined(tiff.MakerNote, append([]byte(nil), b.Bytes()[makerNote:]...)))
+	}
+	exif := b.IFD(entries, 0)
+	b.SetFirst(b.IFD([]tifftest.Entry{b.Long(tiff.ExifIFDPointer, exif)}, 0))
+
+	app1 := append([]byte("Exif\x00\x00"), b.Bytes()...)
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}
+	jpeg = append(jpeg, app1...)
+	return append(jpeg, 0xFF, 0xDA, 0x00, 0x02, 0x00, 0xFF, 0xD9)
+}
+
+func TestReadJPEG(t *testing.T) {
+	m, err := Read(bytes.NewReader(buildTestJPEG(true)))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Canon EOS 5D Mark III", m.ImageType)
+	assert.Equal(t, "Firmware Version 1.3.5", m.FirmwareVersion)
+	assert.Equal(t, "Studio A", m.OwnerName)
+	assert.Equal(t, uint32(0x80000285), m.ModelID)
+	assert.Equal(t, uint32(123456789), m.SerialNumber)
+	assert.Equal(t, "PA1234567", m.InternalSerialNumber)
+	assert.Equal(t, PictureStyleLandscape, m.PictureStyle)
+	assert.Equal(t, "Landscape", m.PictureStyle.String())
+	assert.True(t, m.HasTemperature)
+	assert.Equal(t, 30, m.Temperature)
+}
+
+func TestLensAndFocus(t *testing.T) {
+	m, err := FromJPEG(bytes.NewReader(buildTestJPEG(true)))
+	assert.Nil(t, err)
+
+	assert.Equal(t, Lens{Type: 61182, Model: "EF24-105mm f/4L IS USM", MinFocalLength: 24, MaxFocalLength: 105}, m.Lens)
+	assert.True(t, math.IsInf(m.FocusDistanceUpper, 1))
+	assert.Equal(t, 3.5, m.FocusDistanceLower)
+}
+
+func TestAFPoints(t *testing.T) {
+	m, err := FromJPEG(bytes.NewReader(buildTestJPEG(true)))
+	assert.Nil(t, err)
+
+	assert.NotNil(t, m.AF)
+	assert.Equal(t, 2, m.AF.AreaMode)
+	assert.Equal(t, 5760, m.AF.ImageWidth)
+	assert.Equal(t, []AFPoint{
+		{X: 0, Y: 0, Width: 100, Height: 120, InFocus: true, Selected: true},
+		{X: -100, Y: 50, Width: 100, Height: 120},
+		{X: 100, Y: -50, Width: 100, Height: 120, InFocus: true},
+	}, m.AF.Points)
+	assert.Equal(t, 2, len(m.AF.InFocus()))
+}
+
+func TestNoMakerNote(t *testing.T) {
+	_, err := FromJPEG(bytes.NewReader(buildTestJPEG(false)))
+	assert.Equal(t, ErrNoMakerNote, err)
+}
+
+func TestDecodeSkipsMalformedTags(t *testing.T) {
+	b := tifftest.NewHeaderless(binary.LittleEndian)
+	offset := b.IFD([]tifftest.Entry{
+		b.Short(TagShotInfo, 1, 2, 3),
+		b.ASCII(TagAFInfo2, "not an array"),
+		b.Short(TagProcessingInfo, shorts(15, map[int]uint16{10: 0x99})...),
+	}, 0)
+	ifd, err := tiff.NewHeaderlessReader(bytes.NewReader(b.Bytes()), binary.LittleEndian).ReadIFD(offset)
+	assert.Nil(t, err)
+
+	m := Decode(ifd)
+	assert.False(t, m.HasTemperature)
+	assert.Nil(t, m.AF)
+	assert.Equal(t, "Unknown", m.PictureStyle.String())
+}
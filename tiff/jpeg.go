@@ -0,0 +1,66 @@
+package tiff
+
+import (
+	"errors"
+	"io"
+)
+
+// Identifies the APP1 segment holding Exif data
+var exifHeader = []byte("Exif\x00\x00")
+
+// Returned when a JPEG has no Exif segment
+var ErrNoExif = errors.New("tiff: JPEG has no Exif data")
+
+// Create a Reader for the Exif data in the APP1 segment of a JPEG file.  Its
+// offsets are relative to the TIFF header inside the segment.
+func NewJPEGReader(r io.ReaderAt) (*Reader, error) {
+	start, length, err := FindJPEGExif(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(io.NewSectionReader(r, start, length))
+}
+
+// Find the TIFF structure in the Exif APP1 segment of a JPEG file, returning
+// its offset and length.  The segment's marker and length come just before
+// the Exif header ahead of the structure.  A segment running past the end of
+// the file is ErrFormat.
+func FindJPEGExif(r io.ReaderAt) (int64, int64, error) {
+	marker := make([]byte, 4)
+	if _, err := r.ReadAt(marker[:2], 0); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 0, 0, ErrFormat
+	}
+
+	// segments come one after another until the image data starts
+	for offset := int64(2); ; {
+		if _, err := r.ReadAt(marker, offset); err != nil {
+			return 0, 0, ErrNoExif
+		}
+		if marker[0] != 0xFF {
+			return 0, 0, ErrNoExif
+		}
+		if marker[1] == 0xFF {
+			// fill byte
+			offset++
+			continue
+		}
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 0, 0, ErrNoExif
+		}
+		length := int64(marker[2])<<8 | int64(marker[3])
+		if length < 2 {
+			return 0, 0, ErrNoExif
+		}
+
+		if marker[1] == 0xE1 && length >= 2+int64(len(exifHeader))+8 {
+			header := make([]byte, len(exifHeader))
+			if _, err := r.ReadAt(header, offset+4); err == nil && string(header) == string(exifHeader) {
+				if _, err := r.ReadAt(marker[:1], offset+2+length-1); err != nil {
+					return 0, 0, ErrFormat
+				}
+				return offset + 4 + int64(len(exifHeader)), length - 2 - int64(len(exifHeader)), nil
+			}
+		}
+		offset += 2 + length
+	}
+}
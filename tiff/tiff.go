@@ -0,0 +1,376 @@
+// Package tiff reads the image file directories (IFDs) of TIFF structured
+// data, as used by Canon CR2 files and by Exif metadata in JPEG and CR3
+// files.
+package tiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Data types of IFD entries
+type Type uint16
+
+const (
+	Byte      Type = 1
+	ASCII     Type = 2
+	Short     Type = 3
+	Long      Type = 4
+	Rational  Type = 5
+	SByte     Type = 6
+	Undefined Type = 7
+	SShort    Type = 8
+	SLong     Type = 9
+	SRational Type = 10
+	Float     Type = 11
+	Double    Type = 12
+	IFDType   Type = 13
+)
+
+// Size in bytes of a single value of the type, zero if the type is unknown
+func (t Type) Size() int {
+	switch t {
+	case Byte, ASCII, SByte, Undefined:
+		return 1
+	case Short, SShort:
+		return 2
+	case Long, SLong, Float, IFDType:
+		return 4
+	case Rational, SRational, Double:
+		return 8
+	}
+	return 0
+}
+
+// Tags used to find images and other directories
+const (
+	ImageWidth                  uint16 = 0x0100
+	ImageLength                 uint16 = 0x0101
+	BitsPerSample               uint16 = 0x0102
+	Compression                 uint16 = 0x0103
+	ImageDescription            uint16 = 0x010E
+	Make                        uint16 = 0x010F
+	Model                       uint16 = 0x0110
+	StripOffsets                uint16 = 0x0111
+	Orientation                 uint16 = 0x0112
+	StripByteCounts             uint16 = 0x0117
+	DateTime                    uint16 = 0x0132
+	Artist                      uint16 = 0x013B
+	JPEGInterchangeFormat       uint16 = 0x0201
+	JPEGInterchangeFormatLength uint16 = 0x0202
+	Copyright                   uint16 = 0x8298
+	ExifIFDPointer              uint16 = 0x8769
+	GPSIFDPointer               uint16 = 0x8825
+)
+
+// Tags of the Exif IFD
+const (
+	ExposureTime            uint16 = 0x829A
+	FNumber                 uint16 = 0x829D
+	ExposureProgram         uint16 = 0x8822
+	ISOSpeedRatings         uint16 = 0x8827
+	DateTimeOriginal        uint16 = 0x9003
+	OffsetTimeOriginal      uint16 = 0x9011
+	ExposureBiasValue       uint16 = 0x9204
+	MeteringMode            uint16 = 0x9207
+	Flash                   uint16 = 0x9209
+	FocalLength             uint16 = 0x920A
+	MakerNote               uint16 = 0x927C
+	SubSecTimeOriginal      uint16 = 0x9291
+	FocalLengthIn35mmFilm   uint16 = 0xA405
+	BodySerialNumber        uint16 = 0xA431
+	LensSpecification       uint16 = 0xA432
+	LensModel               uint16 = 0xA434
+	LensSerialNumber        uint16 = 0xA435
+	PixelXDimension         uint16 = 0xA002
+	PixelYDimension         uint16 = 0xA003
+	InteroperabilityPointer uint16 = 0xA005
+)
+
+// Largest value read for a single entry, guarding against corrupt counts
+const maxValueSize = 64 << 20
+
+// Most IFDs followed in a chain, guarding against loops
+const maxChainLength = 64
+
+// Returned when data is not TIFF structured
+var ErrFormat = errors.New("tiff: not a TIFF structure")
+
+// Reads IFDs from TIFF structured data.  All offsets are relative to the
+// start of the reader.
+type Reader struct {
+	r io.ReaderAt
+
+	ByteOrder binary.ByteOrder
+
+	// Offset of the first IFD
+	First uint32
+}
+
+// Create a Reader, checking the TIFF header at the start of r
+func NewReader(r io.ReaderAt) (*Reader, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, ErrFormat
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, ErrFormat
+	}
+	if order.Uint16(header[2:]) != 42 {
+		return nil, ErrFormat
+	}
+	return &Reader{r: r, ByteOrder: order, First: order.Uint32(header[4:])}, nil
+}
+
+// Create a Reader for an IFD with no TIFF header in front of it, such as a
+// maker note, whose offsets are relative to the start of r
+func NewHeaderlessReader(r io.ReaderAt, order binary.ByteOrder) *Reader {
+	return &Reader{r: r, ByteOrder: order}
+}
+
+// Read length bytes at offset
+func (r *Reader) Bytes(offset, length uint32) ([]byte, error) {
+	if length > maxValueSize {
+		return nil, errors.New(fmt.Sprintf("tiff: %d bytes at offset %d is too large to read", length, offset))
+	}
+	data := make([]byte, length)
+	if _, err := r.r.ReadAt(data, int64(offset)); err != nil {
+		return nil, errors.New(fmt.Sprintf("tiff: reading %d bytes at offset %d: %s", length, offset, err))
+	}
+	return data, nil
+}
+
+// Read from the underlying data, so a Reader is itself an io.ReaderAt
+func (r *Reader) ReadAt(p []byte, offset int64) (int, error) {
+	return r.r.ReadAt(p, offset)
+}
+
+// An image file directory
+type IFD struct {
+	Offset  uint32
+	Entries []*Entry
+
+	// Offset of the next IFD in the chain, zero at the end
+	Next uint32
+}
+
+// A tag and its values
+type Entry struct {
+	Tag   uint16
+	Type  Type
+	Count uint32
+
+	// Where the value is stored.  Values of four bytes or less are stored
+	// in the entry itself, so this is the offset of the entry's value
+	// field.
+	Offset uint32
+
+	// Raw value in the file's byte order
+	Data []byte
+
+	order binary.ByteOrder
+}
+
+// Read the IFD at offset
+func (r *Reader) ReadIFD(offset uint32) (*IFD, error) {
+	countData, err := r.Bytes(offset, 2)
+	if err != nil {
+		return nil, err
+	}
+	count := uint32(r.ByteOrder.Uint16(countData))
+	data, err := r.Bytes(offset+2, count*12+4)
+	if err != nil {
+		return nil, err
+	}
+
+	ifd := &IFD{Offset: offset, Next: r.ByteOrder.Uint32(data[count*12:])}
+	for i := uint32(0); i < count; i++ {
+		raw := data[i*12 : i*12+12]
+		entry := &Entry{
+			Tag:    r.ByteOrder.Uint16(raw),
+			Type:   Type(r.ByteOrder.Uint16(raw[2:])),
+			Count:  r.ByteOrder.Uint32(raw[4:]),
+			Offset: offset + 2 + i*12 + 8,
+			order:  r.ByteOrder,
+		}
+		size := uint64(entry.Type.Size()) * uint64(entry.Count)
+		if entry.Type.Size() == 0 {
+			// unknown types are skipped, as the TIFF specification asks
+			continue
+		}
+		if size > maxValueSize {
+			return nil, errors.New(fmt.Sprintf("tiff: tag 0x%04x has too many values (%d)", entry.Tag, entry.Count))
+		}
+		if size <= 4 {
+			entry.Data = raw[8 : 8+size]
+		} else {
+			entry.Offset = r.ByteOrder.Uint32(raw[8:])
+			if entry.Data, err = r.Bytes(entry.Offset, uint32(size)); err != nil {
+				return nil, err
+			}
+		}
+		ifd.Entries = append(ifd.Entries, entry)
+	}
+	return ifd, nil
+}
+
+// Read the chain of IFDs starting with the first
+func (r *Reader) ReadIFDs() ([]*IFD, error) {
+	var ifds []*IFD
+	seen := make(map[uint32]bool)
+	for offset := r.First; offset != 0; {
+		if seen[offset] || len(ifds) == maxChainLength {
+			return nil, errors.New("tiff: IFD chain loops")
+		}
+		seen[offset] = true
+
+		ifd, err := r.ReadIFD(offset)
+		if err != nil {
+			return nil, err
+		}
+		ifds = append(ifds, ifd)
+		offset = ifd.Next
+	}
+	return ifds, nil
+}
+
+// Read the IFD pointed to by a tag of ifd, such as ExifIFDPointer.  Returns
+// nil if ifd doesn't have the tag.
+func (r *Reader) ReadSubIFD(ifd *IFD, tag uint16) (*IFD, error) {
+	entry := ifd.Find(tag)
+	if entry == nil {
+		return nil, nil
+	}
+	offset, err := entry.Uint(0)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadIFD(offset)
+}
+
+// Find the entry for a tag, or nil if there is none
+func (d *IFD) Find(tag uint16) *Entry {
+	if d == nil {
+		return nil
+	}
+	for _, entry := range d.Entries {
+		if entry.Tag == tag {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Value i of an unsigned integer entry
+func (e *Entry) Uint(i int) (uint32, error) {
+	if i < 0 || uint32(i) >= e.Count {
+		return 0, e.indexError(i)
+	}
+	switch e.Type {
+	case Byte, Undefined:
+		return uint32(e.Data[i]), nil
+	case Short:
+		return uint32(e.order.Uint16(e.Data[i*2:])), nil
+	case Long, IFDType:
+		return e.order.Uint32(e.Data[i*4:]), nil
+	}
+	return 0, e.typeError("unsigned integer")
+}
+
+// Value i of a signed or unsigned integer entry
+func (e *Entry) Int(i int) (int64, error) {
+	if i < 0 || uint32(i) >= e.Count {
+		return 0, e.indexError(i)
+	}
+	switch e.Type {
+	case SByte:
+		return int64(int8(e.Data[i])), nil
+	case SShort:
+		return int64(int16(e.order.Uint16(e.Data[i*2:]))), nil
+	case SLong:
+		return int64(int32(e.order.Uint32(e.Data[i*4:]))), nil
+	}
+	value, err := e.Uint(i)
+	if err != nil {
+		return 0, e.typeError("integer")
+	}
+	return int64(value), nil
+}
+
+// Value i of a rational entry as numerator and denominator
+func (e *Entry) Rational(i int) (int64, int64, error) {
+	if i < 0 || uint32(i) >= e.Count {
+		return 0, 0, e.indexError(i)
+	}
+	switch e.Type {
+	case Rational:
+		return int64(e.order.Uint32(e.Data[i*8:])), int64(e.order.Uint32(e.Data[i*8+4:])), nil
+	case SRational:
+		return int64(int32(e.order.Uint32(e.Data[i*8:]))), int64(int32(e.order.Uint32(e.Data[i*8+4:]))), nil
+	}
+	return 0, 0, e.typeError("rational")
+}
+
+// Value i of any numeric entry as a float
+func (e *Entry) Float(i int) (float64, error) {
+	if i < 0 || uint32(i) >= e.Count {
+		return 0, e.indexError(i)
+	}
+	switch e.Type {
+	case Rational, SRational:
+		numerator, denominator, _ := e.Rational(i)
+		if denominator == 0 {
+			return 0, errors.New(fmt.Sprintf("tiff: tag 0x%04x has a zero denominator", e.Tag))
+		}
+		return float64(numerator) / float64(denominator), nil
+	case Float:
+		return float64(math.Float32frombits(e.order.Uint32(e.Data[i*4:]))), nil
+	case Double:
+		return math.Float64frombits(e.order.Uint64(e.Data[i*8:])), nil
+	}
+	value, err := e.Int(i)
+	if err != nil {
+		return 0, e.typeError("number")
+	}
+	return float64(value), nil
+}
+
+// Values of an integer entry
+func (e *Entry) Uints() ([]uint32, error) {
+	values := make([]uint32, e.Count)
+	for i := range values {
+		var err error
+		if values[i], err = e.Uint(i); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Value of an ASCII entry, without trailing NULs or spaces
+func (e *Entry) String() string {
+	value := string(e.Data)
+	if nul := strings.IndexByte(value, 0); nul >= 0 {
+		value = value[:nul]
+	}
+	return strings.TrimRight(value, " ")
+}
+
+func (e *Entry) indexError(i int) error {
+	return errors.New(fmt.Sprintf("tiff: tag 0x%04x has no value %d", e.Tag, i))
+}
+
+func (e *Entry) typeError(want string) error {
+	return errors.New(fmt.Sprintf("tiff: tag 0x%04x of type %d is not a %s", e.Tag, e.Type, want))
+}
@@ -0,0 +1,196 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/internal/tifftest"
+)
+
+func buildTestTIFF(order binary.ByteOrder) []byte {
+	b := tifftest.New(order)
+	exif := b.IFD([]tifftest.Entry{
+		b.Rational(ExposureTime, 1, 250),
+		b.SRational(ExposureBiasValue, -2, 3),
+		b.Short(ISOSpeedRatings, 400),
+	}, 0)
+	second := b.IFD([]tifftest.Entry{b.Long(ImageWidth, 160)}, 0)
+	first := b.IFD([]tifftest.Entry{
+		b.ASCII(Make, "Canon"),
+		b.ASCII(Model, "Canon EOS 5D Mark III"),
+		b.Short(BitsPerSample, 8, 8, 8),
+		b.Long(ExifIFDPointer, exif),
+		{Tag: 0x9999, Type: 99, Count: 1},
+	}, second)
+	b.SetFirst(first)
+	return b.Bytes()
+}
+
+func TestReadIFDs(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		r, err := NewReader(bytes.NewReader(buildTestTIFF(order)))
+		assert.Nil(t, err)
+		assert.Equal(t, order, r.ByteOrder)
+
+		ifds, err := r.ReadIFDs()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(ifds))
+
+		// the entry of unknown type is skipped
+		assert.Equal(t, 4, len(ifds[0].Entries))
+		assert.Equal(t, "Canon", ifds[0].Find(Make).String())
+		assert.Equal(t, "Canon EOS 5D Mark III", ifds[0].Find(Model).String())
+		bits, err := ifds[0].Find(BitsPerSample).Uints()
+		assert.Nil(t, err)
+		assert.Equal(t, []uint32{8, 8, 8}, bits)
+		assert.Nil(t, ifds[0].Find(Orientation))
+
+		width, err := ifds[1].Find(ImageWidth).Uint(0)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(160), width)
+	}
+}
+
+func TestReadSubIFD(t *testing.T) {
+	r, _ := NewReader(bytes.NewReader(buildTestTIFF(binary.LittleEndian)))
+	ifds, _ := r.ReadIFDs()
+
+	exif, err := r.ReadSubIFD(ifds[0], ExifIFDPointer)
+	assert.Nil(t, err)
+	numerator, denominator, err := exif.Find(ExposureTime).Rational(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), numerator)
+	assert.Equal(t, int64(250), denominator)
+	bias, err := exif.Find(ExposureBiasValue).Float(0)
+	assert.Nil(t, err)
+	assert.InDelta(t, -0.667, bias, 0.001)
+	iso, err := exif.Find(ISOSpeedRatings).Int(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(400), iso)
+
+	gps, err := r.ReadSubIFD(ifds[0], GPSIFDPointer)
+	assert.Nil(t, err)
+	assert.Nil(t, gps)
+}
+
+func TestEntryErrors(t *testing.T) {
+	r, _ := NewReader(bytes.NewReader(buildTestTIFF(binary.BigEndian)))
+	ifds, _ := r.ReadIFDs()
+
+	_, err := ifds[0].Find(BitsPerSample).Uint(3)
+	assert.NotNil(t, err)
+	_, _, err = ifds[0].Find(BitsPerSample).Rational(0)
+	assert.NotNil(t, err)
+	_, err = ifds[0].Find(Make).Float(0)
+	assert.NotNil(t, err)
+}
+
+func TestNotTIFF(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("not a tiff file")))
+	assert.Equal(t, ErrFormat, err)
+	_, err = NewReader(bytes.NewReader([]byte("II")))
+	assert.Equal(t, ErrFormat, err)
+}
+
+func TestIFDChainLoop(t *testing.T) {
+	b := tifftest.New(binary.LittleEndian)
+	first := b.IFD([]tifftest.Entry{b.Long(ImageWidth, 1)}, 0)
+	// point the IFD back at itself
+	b.PutUint32(int(first)+2+12, first)
+	b.SetFirst(first)
+
+	r, _ := NewReader(bytes.NewReader(b.Bytes()))
+	_, err := r.ReadIFDs()
+	assert.NotNil(t, err)
+}
+
+func TestTruncatedValue(t *testing.T) {
+	b := tifftest.New(binary.LittleEndian)
+	first := b.IFD([]tifftest.Entry{{Tag: ImageDescription, Type: 2, Count: 1000, Data: make([]byte, 8)}}, 0)
+	b.SetFirst(first)
+
+	r, _ := NewReader(bytes.NewReader(b.Bytes()))
+	_, err := r.ReadIFDs()
+	assert.NotNil(t, err)
+}
+
+func testJPEG(segments ...[]byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	for _, segment := range segments {
+		data = append(data, segment...)
+	}
+	return append(data, 0xFF, 0xDA, 0x00, 0x02, 0x00, 0xFF, 0xD9)
+}
+
+func testSegment(marker byte, payload []byte) []byte {
+	return append([]byte{0xFF, marker, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+}
+
+func TestNewJPEGReader(t *testing.T) {
+	jpeg := testJPEG(
+		testSegment(0xE0, []byte("JFIF\x00\x01\x02")),
+		testSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+		testSegment(0xE1, append([]byte("Exif\x00\x00"), buildTestTIFF(binary.BigEndian)...)),
+	)
+	r, err := NewJPEGReader(bytes.NewReader(jpeg))
+	assert.Nil(t, err)
+	ifds, err := r.ReadIFDs()
+	assert.Nil(t, err)
+	assert.Equal(t, "Canon", ifds[0].Find(Make).String())
+
+	exif, err := r.ReadSubIFD(ifds[0], ExifIFDPointer)
+	assert.Nil(t, err)
+	assert.NotNil(t, exif.Find(ExposureTime))
+}
+
+func TestNewJPEGReaderWithoutExif(t *testing.T) {
+	_, err := NewJPEGReader(bytes.NewReader(testJPEG(testSegment(0xE0, []byte("JFIF\x00")))))
+	assert.Equal(t, ErrNoExif, err)
+	_, err = NewJPEGReader(bytes.NewReader(buildTestTIFF(binary.LittleEndian)))
+	assert.Equal(t, ErrFormat, err)
+}
+
+func TestEncodeIFD(t *testing.T) {
+	// append a new IFD0 to an existing structure, keeping its entries and
+	// adding one
+	data := buildTestTIFF(binary.BigEndian)
+	r, _ := NewReader(bytes.NewReader(data))
+	ifd0, _ := r.ReadIFD(r.First)
+
+	entries := append([]*Entry{}, ifd0.Entries...)
+	entries = append(entries, NewEntry(binary.BigEndian, Artist, ASCII, 9, []byte("Studio A\x00")))
+	offset := uint32(len(data))
+	data = append(data, EncodeIFD(binary.BigEndian, offset, entries, ifd0.Next)...)
+	binary.BigEndian.PutUint32(data[4:], offset)
+
+	r, _ = NewReader(bytes.NewReader(data))
+	ifds, err := r.ReadIFDs()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ifds))
+	assert.Equal(t, "Studio A", ifds[0].Find(Artist).String())
+	assert.Equal(t, "Canon EOS 5D Mark III", ifds[0].Find(Model).String())
+	bits, _ := ifds[0].Find(BitsPerSample).Uints()
+	assert.Equal(t, []uint32{8, 8, 8}, bits)
+
+	exif, err := r.ReadSubIFD(ifds[0], ExifIFDPointer)
+	assert.Nil(t, err)
+	assert.NotNil(t, exif.Find(ExposureTime))
+}
+
+func TestParseTime(t *testing.T) {
+	defer func(location *time.Location) { CameraLocation = location }(CameraLocation)
+	CameraLocation = time.FixedZone("+10:00", 10*3600)
+
+	// without an offset the camera's clock is taken to be in CameraLocation
+	taken := ParseTime("2015:06:01 12:30:45", "25", "")
+	assert.True(t, taken.Equal(time.Date(2015, 6, 1, 2, 30, 45, 250000000, time.UTC)))
+
+	taken = ParseTime("2015:06:01 12:30:45", "", "-05:00")
+	assert.True(t, taken.Equal(time.Date(2015, 6, 1, 17, 30, 45, 0, time.UTC)))
+
+	assert.True(t, ParseTime("", "", "").IsZero())
+	assert.True(t, ParseTime("    :  :     :  :  ", "", "").IsZero())
+}
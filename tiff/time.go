@@ -0,0 +1,48 @@
+package tiff
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Location that camera clock times without an offset from UTC are taken to
+// be in, by this package's callers and by the eos package for the times of
+// files on a card.  Camera clocks keep the time of wherever they were set
+// and, unless the body records OffsetTime tags, files don't say where that
+// was, so it defaults to the host's own time zone.  Set it to the zone the
+// camera's clock was set in when that differs.
+var CameraLocation = time.Local
+
+// Layout of Exif date and time values
+const timeLayout = "2006:01:02 15:04:05"
+
+// Parse a date and time value, such as DateTimeOriginal, with its fraction of
+// a second and offset from UTC, both of which may be empty.  Without an
+// offset the time is taken to be in CameraLocation.  The zero time is
+// returned if the value is empty or malformed.
+func ParseTime(value string, subSeconds string, offset string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	location := CameraLocation
+	if offset != "" {
+		if zone, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := zone.Zone()
+			location = time.FixedZone(offset, seconds)
+		}
+	}
+	taken, err := time.ParseInLocation(timeLayout, value, location)
+	if err != nil {
+		return time.Time{}
+	}
+
+	// the digits are a decimal fraction, so "5" is half a second
+	subSeconds = strings.TrimSpace(subSeconds)
+	if subSeconds != "" && len(subSeconds) <= 9 {
+		if nanoseconds, err := strconv.Atoi(subSeconds + strings.Repeat("0", 9-len(subSeconds))); err == nil && nanoseconds >= 0 {
+			taken = taken.Add(time.Duration(nanoseconds))
+		}
+	}
+	return taken
+}
@@ -0,0 +1,40 @@
+package tiff
+
+import (
+	"encoding/binary"
+)
+
+// Create an entry from its raw value in the given byte order
+func NewEntry(order binary.ByteOrder, tag uint16, t Type, count uint32, data []byte) *Entry {
+	return &Entry{Tag: tag, Type: t, Count: count, Data: data, order: order}
+}
+
+// Encode an IFD to be written at offset in a TIFF structure, followed by the
+// values too large to fit in its entries.  The entries are written in the
+// order given, which the TIFF specification asks to be by tag, and their
+// values must be in the structure's byte order.
+func EncodeIFD(order binary.ByteOrder, offset uint32, entries []*Entry, next uint32) []byte {
+	size := 2 + 12*len(entries) + 4
+	data := make([]byte, size)
+	order.PutUint16(data, uint16(len(entries)))
+
+	for i, entry := range entries {
+		field := data[2+12*i:]
+		order.PutUint16(field, entry.Tag)
+		order.PutUint16(field[2:], uint16(entry.Type))
+		order.PutUint32(field[4:], entry.Count)
+		if len(entry.Data) <= 4 {
+			copy(field[8:12], entry.Data)
+			continue
+		}
+
+		// values start on a word boundary
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+		order.PutUint32(field[8:], offset+uint32(len(data)))
+		data = append(data, entry.Data...)
+	}
+	order.PutUint32(data[2+12*len(entries):], next)
+	return data
+}
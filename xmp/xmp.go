@@ -0,0 +1,234 @@
+// Package xmp writes Adobe XMP sidecar files, which Lightroom, Capture One
+// and other editors read alongside RAW files on import.
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/urlgrey/canon-eos-go/exif"
+)
+
+// Namespaces of the properties a sidecar holds
+const (
+	namespaceXMP       = "http://ns.adobe.com/xap/1.0/"
+	namespaceDC        = "http://purl.org/dc/elements/1.1/"
+	namespaceXMPRights = "http://ns.adobe.com/xap/1.0/rights/"
+	namespaceExif      = "http://ns.adobe.com/exif/1.0/"
+)
+
+// Prefixes taken by the sidecar itself, which custom namespaces can't use
+var reservedPrefixes = map[string]bool{
+	"x": true, "rdf": true, "xml": true, "xmlns": true, "xmp": true, "dc": true, "xmpRights": true, "exif": true,
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Rating of a picture marked as rejected
+const Rejected = -1
+
+// Properties in a namespace of your own, such as a product SKU
+type Namespace struct {
+	// Prefix used in the file, such as "sku", and the URI identifying the
+	// namespace, such as "http://example.com/ns/sku/1.0/"
+	Prefix string
+	URI    string
+
+	// Values keyed by property name
+	Properties map[string]string
+}
+
+// Metadata written to a sidecar.  Empty fields are left out.
+type Sidecar struct {
+	// Star rating from 0 to 5, or Rejected
+	Rating int
+
+	// Colour label, such as "Red", as named in the editor's label set
+	Label string
+
+	Keywords  []string
+	Title     string
+	Creator   string
+	Copyright string
+
+	// Position the picture was taken at, nil if unknown
+	GPS *exif.GPS
+
+	Custom []Namespace
+}
+
+// Path of the sidecar for a file, which editors expect to share its name
+// with the extension replaced, so IMG_0001.CR2 has IMG_0001.xmp
+func SidecarPath(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".xmp"
+}
+
+// Check the rating and custom namespaces can be written
+func (s *Sidecar) Validate() error {
+	if s.Rating < Rejected || s.Rating > 5 {
+		return errors.New(fmt.Sprintf("xmp: rating %d is not between -1 and 5", s.Rating))
+	}
+	prefixes := make(map[string]bool)
+	for _, namespace := range s.Custom {
+		if !namePattern.MatchString(namespace.Prefix) || strings.Contains(namespace.Prefix, ":") {
+			return errors.New(fmt.Sprintf("xmp: invalid namespace prefix %q", namespace.Prefix))
+		}
+		if reservedPrefixes[namespace.Prefix] || prefixes[namespace.Prefix] {
+			return errors.New(fmt.Sprintf("xmp: namespace prefix %q is already in use", namespace.Prefix))
+		}
+		if namespace.URI == "" {
+			return errors.New(fmt.Sprintf("xmp: namespace %q has no URI", namespace.Prefix))
+		}
+		prefixes[namespace.Prefix] = true
+		for name := range namespace.Properties {
+			if !namePattern.MatchString(name) {
+				return errors.New(fmt.Sprintf("xmp: invalid property name %q in namespace %q", name, namespace.Prefix))
+			}
+		}
+	}
+	return nil
+}
+
+// Write the sidecar as an XMP packet
+func (s *Sidecar) Encode(w io.Writer) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\xEF\xBB\xBF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"")
+
+	// simple values are written as attributes
+	attribute(&b, "xmlns:xmp", namespaceXMP)
+	attribute(&b, "xmlns:dc", namespaceDC)
+	attribute(&b, "xmlns:xmpRights", namespaceXMPRights)
+	if s.GPS != nil {
+		attribute(&b, "xmlns:exif", namespaceExif)
+	}
+	for _, namespace := range s.Custom {
+		attribute(&b, "xmlns:"+namespace.Prefix, namespace.URI)
+	}
+	if s.Rating != 0 {
+		attribute(&b, "xmp:Rating", fmt.Sprint(s.Rating))
+	}
+	if s.Label != "" {
+		attribute(&b, "xmp:Label", s.Label)
+	}
+	if s.Copyright != "" {
+		attribute(&b, "xmpRights:Marked", "True")
+	}
+	if s.GPS != nil {
+		writeGPS(&b, s.GPS)
+	}
+	for _, namespace := range s.Custom {
+		names := make([]string, 0, len(namespace.Properties))
+		for name := range namespace.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			attribute(&b, namespace.Prefix+":"+name, namespace.Properties[name])
+		}
+	}
+	b.WriteString(">\n")
+
+	// language alternatives, ordered lists and unordered bags are elements
+	if s.Title != "" {
+		array(&b, "dc:title", "rdf:Alt", []string{s.Title})
+	}
+	if s.Creator != "" {
+		array(&b, "dc:creator", "rdf:Seq", []string{s.Creator})
+	}
+	if s.Copyright != "" {
+		array(&b, "dc:rights", "rdf:Alt", []string{s.Copyright})
+	}
+	if len(s.Keywords) > 0 {
+		array(&b, "dc:subject", "rdf:Bag", s.Keywords)
+	}
+
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Write the sidecar to the named file, replacing it in one step so an editor
+// never reads it half written
+func (s *Sidecar) WriteFile(name string) error {
+	var b bytes.Buffer
+	if err := s.Encode(&b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".part", b.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".part", name)
+}
+
+// Write a position the way Exif properties are written in XMP, coordinates
+// as degrees and decimal minutes such as "33,51.5940S"
+func writeGPS(b *bytes.Buffer, gps *exif.GPS) {
+	attribute(b, "exif:GPSVersionID", "2.3.0.0")
+	attribute(b, "exif:GPSLatitude", coordinate(gps.Latitude, "N", "S"))
+	attribute(b, "exif:GPSLongitude", coordinate(gps.Longitude, "E", "W"))
+	if gps.HasAltitude {
+		ref := "0"
+		if gps.Altitude < 0 {
+			ref = "1"
+		}
+		attribute(b, "exif:GPSAltitudeRef", ref)
+		attribute(b, "exif:GPSAltitude", fmt.Sprintf("%d/100", int64(math.Round(math.Abs(gps.Altitude)*100))))
+	}
+	if !gps.Time.IsZero() {
+		attribute(b, "exif:GPSTimeStamp", gps.Time.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+}
+
+func coordinate(value float64, positive string, negative string) string {
+	direction := positive
+	if value < 0 {
+		direction, value = negative, -value
+	}
+	degrees := math.Floor(value)
+	return fmt.Sprintf("%d,%.4f%s", int(degrees), (value-degrees)*60, direction)
+}
+
+func attribute(b *bytes.Buffer, name string, value string) {
+	b.WriteString("\n    ")
+	b.WriteString(name)
+	b.WriteString("=\"")
+	escape(b, value)
+	b.WriteString("\"")
+}
+
+func array(b *bytes.Buffer, property string, kind string, values []string) {
+	fmt.Fprintf(b, "   <%s>\n    <%s>\n", property, kind)
+	for _, value := range values {
+		if kind == "rdf:Alt" {
+			b.WriteString("     <rdf:li xml:lang=\"x-default\">")
+		} else {
+			b.WriteString("     <rdf:li>")
+		}
+		escape(b, value)
+		b.WriteString("</rdf:li>\n")
+	}
+	fmt.Fprintf(b, "    </%s>\n   </%s>\n", kind, property)
+}
+
+func escape(b *bytes.Buffer, value string) {
+	xml.EscapeText(b, []byte(value))
+}
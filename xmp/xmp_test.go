@@ -0,0 +1,127 @@
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urlgrey/canon-eos-go/exif"
+)
+
+// Just enough of the packet to check what was written
+type testPacket struct {
+	Description struct {
+		Rating   string   `xml:"http://ns.adobe.com/xap/1.0/ Rating,attr"`
+		Label    string   `xml:"http://ns.adobe.com/xap/1.0/ Label,attr"`
+		Marked   string   `xml:"http://ns.adobe.com/xap/1.0/rights/ Marked,attr"`
+		SKU      string   `xml:"http://example.com/ns/product/1.0/ SKU,attr"`
+		Session  string   `xml:"http://example.com/ns/product/1.0/ Session,attr"`
+		Title    []string `xml:"title>Alt>li"`
+		Creator  []string `xml:"creator>Seq>li"`
+		Rights   []string `xml:"rights>Alt>li"`
+		Keywords []string `xml:"subject>Bag>li"`
+	} `xml:"RDF>Description"`
+}
+
+func newTestSidecar() *Sidecar {
+	return &Sidecar{
+		Rating:    4,
+		Label:     "Red",
+		Keywords:  []string{"catalogue", "spring & summer"},
+		Title:     "Linen shirt <front>",
+		Creator:   "Studio A",
+		Copyright: "© 2015 Example Ltd",
+		Custom: []Namespace{{
+			Prefix:     "product",
+			URI:        "http://example.com/ns/product/1.0/",
+			Properties: map[string]string{"SKU": "SH-1042-\"BL\"", "Session": "2015-06-01 morning"},
+		}},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, newTestSidecar().Encode(&b))
+
+	var packet testPacket
+	assert.Nil(t, xml.Unmarshal(b.Bytes(), &packet))
+	description := packet.Description
+	assert.Equal(t, "4", description.Rating)
+	assert.Equal(t, "Red", description.Label)
+	assert.Equal(t, "True", description.Marked)
+	assert.Equal(t, "SH-1042-\"BL\"", description.SKU)
+	assert.Equal(t, "2015-06-01 morning", description.Session)
+	assert.Equal(t, []string{"Linen shirt <front>"}, description.Title)
+	assert.Equal(t, []string{"Studio A"}, description.Creator)
+	assert.Equal(t, []string{"© 2015 Example Ltd"}, description.Rights)
+	assert.Equal(t, []string{"catalogue", "spring & summer"}, description.Keywords)
+
+	assert.True(t, bytes.HasPrefix(b.Bytes(), []byte("<?xpacket begin=")))
+	assert.True(t, bytes.HasSuffix(b.Bytes(), []byte("<?xpacket end=\"w\"?>\n")))
+}
+
+func TestEncodeLeavesOutEmptyFields(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, (&Sidecar{Label: "Green"}).Encode(&b))
+	assert.NotContains(t, b.String(), "xmp:Rating")
+	assert.NotContains(t, b.String(), "dc:subject")
+	assert.NotContains(t, b.String(), "xmpRights:Marked")
+	assert.Contains(t, b.String(), "xmp:Label=\"Green\"")
+
+	b.Reset()
+	assert.Nil(t, (&Sidecar{Rating: Rejected}).Encode(&b))
+	assert.Contains(t, b.String(), "xmp:Rating=\"-1\"")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NotNil(t, (&Sidecar{Rating: 6}).Validate())
+	assert.NotNil(t, (&Sidecar{Custom: []Namespace{{Prefix: "dc", URI: "http://example.com/"}}}).Validate())
+	assert.NotNil(t, (&Sidecar{Custom: []Namespace{{Prefix: "a b", URI: "http://example.com/"}}}).Validate())
+	assert.NotNil(t, (&Sidecar{Custom: []Namespace{{Prefix: "sku"}}}).Validate())
+	assert.NotNil(t, (&Sidecar{Custom: []Namespace{
+		{Prefix: "sku", URI: "http://example.com/a/"},
+		{Prefix: "sku", URI: "http://example.com/b/"},
+	}}).Validate())
+	assert.NotNil(t, (&Sidecar{Custom: []Namespace{
+		{Prefix: "sku", URI: "http://example.com/", Properties: map[string]string{"1st": "x"}},
+	}}).Validate())
+	assert.Nil(t, newTestSidecar().Validate())
+}
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "IMG_0001.xmp")
+	assert.Nil(t, newTestSidecar().WriteFile(name))
+
+	data, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "xmp:Rating=\"4\"")
+	_, err = os.Stat(name + ".part")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSidecarPath(t *testing.T) {
+	assert.Equal(t, "/photos/IMG_0001.xmp", SidecarPath("/photos/IMG_0001.CR2"))
+	assert.Equal(t, "shoot.v2/IMG_0001.xmp", SidecarPath("shoot.v2/IMG_0001.CR3"))
+	assert.Equal(t, "IMG_0001.xmp", SidecarPath("IMG_0001"))
+}
+
+func TestEncodeGPS(t *testing.T) {
+	taken := time.Date(2015, 6, 1, 2, 30, 44, 0, time.UTC)
+	sidecar := &Sidecar{GPS: &exif.GPS{Latitude: -33.8599, Longitude: 151.2083, Altitude: -7.5, HasAltitude: true, Time: taken}}
+	var b bytes.Buffer
+	assert.Nil(t, sidecar.Encode(&b))
+
+	assert.Contains(t, b.String(), "xmlns:exif=\"http://ns.adobe.com/exif/1.0/\"")
+	assert.Contains(t, b.String(), "exif:GPSLatitude=\"33,51.5940S\"")
+	assert.Contains(t, b.String(), "exif:GPSLongitude=\"151,12.4980E\"")
+	assert.Contains(t, b.String(), "exif:GPSAltitudeRef=\"1\"")
+	assert.Contains(t, b.String(), "exif:GPSAltitude=\"750/100\"")
+	assert.Contains(t, b.String(), "exif:GPSTimeStamp=\"2015-06-01T02:30:44Z\"")
+
+	var packet testPacket
+	assert.Nil(t, xml.Unmarshal(b.Bytes(), &packet))
+}